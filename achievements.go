@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	toastDuration = 3 * time.Second // How long an unlock notification stays up
+	hatTrickTime  = time.Second     // Window for three kills to count as a hat trick
+)
+
+// achievement is something worth doing. Like the stats, only player one can
+// earn them.
+type achievement struct {
+	ID    string // Key in the saved file; never change it
+	Name  string
+	Desc  string
+	check func(g *Game) bool // Whether it has just been earned
+}
+
+var achievements = []achievement{
+	{"first-blood", "First Blood", "Kill an enemy", func(g *Game) bool {
+		return g.runStats.Kills > 0
+	}},
+	{"headhunter", "Headhunter", "Decapitate 10 enemies", func(g *Game) bool {
+		return g.lifetime.Decapitations+g.runStats.Decapitations >= 10
+	}},
+	{"centurion", "Centurion", "Kill 100 enemies", func(g *Game) bool {
+		return g.lifetime.Kills+g.runStats.Kills >= 100
+	}},
+	{"hat-trick", "Hat Trick", "Kill 3 enemies within a second", func(g *Game) bool {
+		n := len(g.recentKills)
+		return n >= 3 && g.recentKills[n-1].Sub(g.recentKills[n-3]) <= hatTrickTime
+	}},
+	{"living-dead", "Night of the Living Dead", "Have 3 headless bodies running around at once", func(g *Game) bool {
+		n := 0
+		for i := range g.corpses {
+			if g.corpses[i].Active {
+				n++
+			}
+		}
+		return n >= 3
+	}},
+	{"sharpshooter", "Sharpshooter", "Hit 10 shuriken in a run without a miss", func(g *Game) bool {
+		return g.runStats.ShotsHit >= 10 && g.runStats.ShotsHit == g.runStats.ShotsFired
+	}},
+	{"grounded", "Grounded", "Survive 5 minutes without jumping", func(g *Game) bool {
+		p := &g.players[0]
+		return !p.Dead && p.Jumps == 0 && g.now.Sub(g.runStart) >= 5*time.Minute
+	}},
+	{"finish-line", "Finish Line", "Clear a stage", func(g *Game) bool {
+		return g.stageClear
+	}},
+}
+
+// toast is a notification shown briefly during play
+type toast struct {
+	Text  string
+	Until time.Time // Game clock time it goes away
+}
+
+// AchievementBook records when each profile unlocked its achievements
+type AchievementBook struct {
+	mu       sync.Mutex
+	path     string // File the achievements are saved to ("" to keep them in memory)
+	profiles map[string]map[string]time.Time
+}
+
+// achievementsPath is where unlocked achievements are kept
+func achievementsPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "gninja", "achievements.json")
+}
+
+// loadAchievementBook reads the achievements saved at path. A missing or
+// unreadable file means nobody has unlocked anything yet.
+func loadAchievementBook(path string) *AchievementBook {
+	b := &AchievementBook{path: path, profiles: make(map[string]map[string]time.Time)}
+	if path == "" {
+		return b
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &b.profiles)
+	}
+	return b
+}
+
+// Unlocked returns when each of a profile's achievements was unlocked
+func (b *AchievementBook) Unlocked(profile string) map[string]time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	unlocked := make(map[string]time.Time, len(b.profiles[profile]))
+	for id, at := range b.profiles[profile] {
+		unlocked[id] = at
+	}
+	return unlocked
+}
+
+// Unlock records an achievement for a profile and saves the book. It reports
+// false if the profile already had it.
+func (b *AchievementBook) Unlock(profile, id string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.profiles[profile] == nil {
+		b.profiles[profile] = make(map[string]time.Time)
+	}
+	if _, ok := b.profiles[profile][id]; ok {
+		return false, nil
+	}
+	b.profiles[profile][id] = time.Now()
+
+	if b.path == "" {
+		return true, nil
+	}
+	return true, writeJSONFile(b.path, b.profiles)
+}
+
+// loadProfile picks up the player's lifetime stats and achievements at the
+// start of a run, so they can be checked every frame without locking
+func (g *Game) loadProfile() {
+	g.lifetime = Stats{}
+	if g.stats != nil {
+		g.lifetime = g.stats.Get(g.playerName)
+	}
+	g.achieved = make(map[string]bool)
+	if g.achievements != nil {
+		for id := range g.achievements.Unlocked(g.playerName) {
+			g.achieved[id] = true
+		}
+	}
+	g.recentKills = nil
+}
+
+// achievementEvent remembers when player one last killed something, for hat
+// tricks. Everything else is checked against the game's state.
+func (g *Game) achievementEvent(ev Event) {
+	if ev, ok := ev.(EnemyKilled); ok && g.countsForStats(ev.By) {
+		g.recentKills = append(g.recentKills, g.now)
+		if len(g.recentKills) > 3 {
+			g.recentKills = g.recentKills[1:]
+		}
+	}
+}
+
+// checkAchievements unlocks whatever player one has just earned
+func (g *Game) checkAchievements() {
+	if g.achievements == nil || g.editor != nil || g.inMenu || len(g.players) == 0 {
+		return
+	}
+	for _, a := range achievements {
+		if g.achieved[a.ID] || !a.check(g) {
+			continue
+		}
+		g.achieved[a.ID] = true
+		unlocked, err := g.achievements.Unlock(g.playerName, a.ID)
+		if unlocked {
+			g.toast("Achievement unlocked: " + a.Name)
+		}
+		if err != nil {
+			g.toast("Achievements not saved: " + err.Error())
+		}
+	}
+}
+
+// toast shows a notification for a few seconds
+func (g *Game) toast(text string) {
+	live := g.toasts[:0]
+	for _, t := range g.toasts {
+		if g.now.Before(t.Until) {
+			live = append(live, t)
+		}
+	}
+	g.toasts = append(live, toast{Text: text, Until: g.now.Add(toastDuration)})
+}
+
+// drawToasts draws the notifications that are up in the top right corner,
+// newest at the top
+func (g *Game) drawToasts() {
+	y := 1
+	style := tcell.StyleDefault.Foreground(tcell.ColorYellow)
+	for i := len(g.toasts) - 1; i >= 0; i-- {
+		t := g.toasts[i]
+		if !g.now.Before(t.Until) {
+			continue
+		}
+		text := "★ " + t.Text + " "
+		g.drawText(g.width-len([]rune(text)), y, text, style)
+		y++
+	}
+}
+
+// drawAchievementGallery draws the achievements page of the menu
+func (g *Game) drawAchievementGallery() {
+	var unlocked map[string]time.Time
+	if g.achievements != nil {
+		unlocked = g.achievements.Unlocked(g.playerName)
+	}
+
+	title := fmt.Sprintf("Achievements: %d of %d", len(unlocked), len(achievements))
+	titleY := max(1, g.height/2-len(achievements)-1)
+	g.drawText((g.width-len(title))/2, titleY, title, tcell.StyleDefault.Foreground(tcell.ColorGreen))
+
+	width := 0
+	for _, a := range achievements {
+		width = max(width, len(a.Desc)+2, len(a.Name)+len(" (2006-01-02)")+2)
+	}
+	x := (g.width - width) / 2
+	y := titleY + 2
+	for _, a := range achievements {
+		nameStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
+		name := "  " + a.Name
+		if at, ok := unlocked[a.ID]; ok {
+			nameStyle = tcell.StyleDefault.Foreground(tcell.ColorYellow)
+			name = fmt.Sprintf("★ %s (%s)", a.Name, at.Format("2006-01-02"))
+		}
+		g.drawText(x, y, name, nameStyle)
+		g.drawText(x+2, y+1, a.Desc, tcell.StyleDefault)
+		y += 2
+	}
+
+	help := "ESC to go back"
+	g.drawText((g.width-len(help))/2, y+1, help, tcell.StyleDefault)
+}
+
+func (g *Game) handleAchievementsInput(ev *tcell.EventKey) {
+	if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyEnter {
+		g.menuScreen = screenMain
+	}
+}
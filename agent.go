@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Agent mode runs the game without a terminal for bots to play, in the style
+// of a Gym environment. Each line read from stdin is a JSON action or reset
+// command; each step plays one frame (1/FPS of a second) as fast as it can and
+// writes one JSON line to stdout with what the player can see, the reward for
+// the step and whether the run is over.
+
+const (
+	agentWidth  = 100 // Size of the pretend screen levels are laid out for
+	agentHeight = 30
+
+	rewardDeath = -50  // Reward for losing a life
+	rewardClear = 100  // Reward for reaching a stage's goal
+	agentSeed   = 1234 // Level seed when neither --seed nor a reset picks one
+)
+
+// agentInput is a line from the agent: an action, or a reset
+type agentInput struct {
+	Move  int    `json:"move"`  // -1 left, 1 right, 0 stand still
+	Jump  bool   `json:"jump"`  // Hold jump
+	Throw bool   `json:"throw"` // Throw a shuriken (if the cooldown allows)
+	Reset bool   `json:"reset"` // Start a new run instead of stepping
+	Seed  *int64 `json:"seed"`  // Level seed for the new run (with reset)
+}
+
+// agentStep is a line to the agent
+type agentStep struct {
+	Obs    agentObs `json:"obs"`
+	Reward float64  `json:"reward"`
+	Done   bool     `json:"done"`
+	Error  string   `json:"error,omitempty"` // What was wrong with the input line
+}
+
+// agentObs is what the agent can see
+type agentObs struct {
+	Step        int               `json:"step"`
+	Time        float64           `json:"time"` // Seconds into the run
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	GroundY     int               `json:"groundY"`
+	Goal        float64           `json:"goal,omitempty"` // X of the stage goal (0 on arenas)
+	Score       int               `json:"score"`
+	Player      agentPlayer       `json:"player"`
+	Enemies     []agentEnemy      `json:"enemies"`
+	Projectiles []agentProjectile `json:"projectiles"`
+	Platforms   []Platform        `json:"platforms"`
+	Hazards     []Hazard          `json:"hazards"`
+}
+
+type agentPlayer struct {
+	Pos      Vec2    `json:"pos"`
+	Vel      Vec2    `json:"vel"`
+	Width    int     `json:"width"`
+	Height   int     `json:"height"`
+	Facing   int     `json:"facing"`
+	OnGround bool    `json:"onGround"` // On the ground or a platform, able to jump
+	Lives    int     `json:"lives"`
+	Dead     bool    `json:"dead"`
+	CanThrow bool    `json:"canThrow"` // Throw cooldown is over
+	SafeFor  float64 `json:"safeFor"`  // Seconds of respawn grace left
+}
+
+type agentEnemy struct {
+	ID       int    `json:"id"`
+	Kind     string `json:"kind"`
+	Pos      Vec2   `json:"pos"`
+	Vel      Vec2   `json:"vel"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Facing   int    `json:"facing"`
+	CanShoot bool   `json:"canShoot"`
+	Pattern  string `json:"pattern,omitempty"` // How a shooter fires: "burst", "spread" or "lob"
+}
+
+type agentProjectile struct {
+	Pos     Vec2    `json:"pos"`
+	Vel     Vec2    `json:"vel"`
+	Gravity float64 `json:"gravity"` // Pulls a lobbed shot down
+	Dir     int     `json:"dir"`
+	Enemy   bool    `json:"enemy"` // Thrown by an enemy, so it can hurt the player
+}
+
+// newAgentScreen is the screen the game draws on in agent mode, which nobody sees
+func newAgentScreen() (tcell.Screen, error) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		return nil, err
+	}
+	s.SetSize(agentWidth, agentHeight)
+	return s, nil
+}
+
+// runAgent plays runs for an agent until its input ends
+func (g *Game) runAgent(in io.Reader, out io.Writer) error {
+	// Runs aren't saved or recorded, and nothing goes on the high score table
+	g.ghostsOn = false
+	g.savePath = ""
+	if g.levelSeed == 0 {
+		g.levelSeed = agentSeed
+	}
+
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	send := func(step agentStep) error {
+		if err := enc.Encode(step); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+
+	steps := 0
+	g.agentReset()
+	if err := send(agentStep{Obs: g.agentObserve(steps)}); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		var input agentInput
+		if err := json.Unmarshal(scanner.Bytes(), &input); err != nil {
+			err := send(agentStep{Obs: g.agentObserve(steps), Done: g.gameOver, Error: fmt.Sprintf("bad input: %v", err)})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if input.Reset {
+			if input.Seed != nil {
+				g.levelSeed = *input.Seed
+			}
+			steps = 0
+			g.agentReset()
+			if err := send(agentStep{Obs: g.agentObserve(steps)}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		reward := 0.0
+		if !g.gameOver {
+			reward = g.agentStep(input)
+			steps++
+		}
+		if err := send(agentStep{Obs: g.agentObserve(steps), Reward: reward, Done: g.gameOver}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// agentReset starts a new solo run on the selected level
+func (g *Game) agentReset() {
+	g.numPlayers = 1
+	g.match = nil
+	g.inMenu = false
+	g.buildLevel()
+	g.resetRun()
+}
+
+// agentStep plays one frame with the agent's action held, returning the reward
+func (g *Game) agentStep(input agentInput) float64 {
+	p := &g.players[0]
+	score, lives := p.Score, p.Lives
+
+	// Actions are held for exactly this step, unlike keys which stay held for
+	// keyTimeout after the last press
+	held := map[Action]bool{
+		ActionLeft:  input.Move < 0,
+		ActionRight: input.Move > 0,
+		ActionJump:  input.Jump,
+	}
+	for action, on := range held {
+		p.Pressed[action] = time.Time{}
+		if on {
+			p.Pressed[action] = g.now
+		}
+	}
+	if input.Throw {
+		g.pressAction(p, ActionThrow)
+	}
+
+	g.update(FrameDuration.Seconds())
+
+	reward := float64(p.Score - score)
+	if p.Lives < lives {
+		reward += rewardDeath
+	}
+	if g.stageClear {
+		reward += rewardClear
+	}
+	return reward
+}
+
+// agentObserve describes the game as the agent sees it after a number of steps
+func (g *Game) agentObserve(step int) agentObs {
+	p := &g.players[0]
+	obs := agentObs{
+		Step:    step,
+		Time:    g.now.Sub(g.runStart).Seconds(),
+		Width:   g.worldWidth,
+		Height:  g.height,
+		GroundY: g.groundY,
+		Goal:    g.goalX,
+		Score:   p.Score,
+		Player: agentPlayer{
+			Pos:      p.Pos,
+			Vel:      p.Vel,
+			Width:    p.Width,
+			Height:   p.Height,
+			Facing:   p.Facing,
+			OnGround: p.OnGround || p.OnPlatform,
+			Lives:    p.Lives,
+			Dead:     p.Dead,
+			CanThrow: g.now.Sub(p.LastShot) > g.difficulty.ThrowCooldown,
+			SafeFor:  max(0, p.SafeUntil.Sub(g.now).Seconds()),
+		},
+		Enemies:     []agentEnemy{},
+		Projectiles: []agentProjectile{},
+		Platforms:   g.platforms,
+		Hazards:     g.hazards,
+	}
+	for i := range g.enemies {
+		e := &g.enemies[i]
+		if !e.Active {
+			continue
+		}
+		obs.Enemies = append(obs.Enemies, agentEnemy{
+			ID:       e.ID,
+			Kind:     e.Kind,
+			Pos:      e.Pos,
+			Vel:      e.Vel,
+			Width:    e.Width,
+			Height:   e.Height,
+			Facing:   e.Facing,
+			CanShoot: e.CanShoot,
+			Pattern:  e.Pattern,
+		})
+	}
+	for i := range g.projectiles {
+		pr := &g.projectiles[i]
+		if pr.Active {
+			obs.Projectiles = append(obs.Projectiles, agentProjectile{
+				Pos:     pr.Pos,
+				Vel:     pr.velocity(),
+				Gravity: pr.Gravity,
+				Dir:     pr.Dir,
+				Enemy:   pr.Owner == ownerEnemy,
+			})
+		}
+	}
+	if obs.Platforms == nil {
+		obs.Platforms = []Platform{}
+	}
+	if obs.Hazards == nil {
+		obs.Hazards = []Hazard{}
+	}
+	return obs
+}
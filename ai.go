@@ -0,0 +1,206 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// The AI player plays through pressAction, the same way a human's keys do, so
+// its ninja obeys the same rules: held keys linger for keyTimeout, shuriken
+// fly the way the ninja is facing and throwing has a cooldown. It plays the
+// attract-mode demo behind the menu and the games of a soak test.
+
+const (
+	aiDodgeTime   = 300 * time.Millisecond // How soon an incoming shuriken has to hit before the AI jumps it
+	aiThreatRange = 40.0                   // Columns within which an enemy is worth fighting
+	aiThreatRise  = 12.0                   // Rows above or below within which an enemy is worth fighting
+	aiKeepAway    = 15.0                   // Columns kept from an enemy that can't be shot yet
+	aiArrived     = 3.0                    // Columns from its target at which the AI stops walking
+	aiHazardLook  = 4.0                    // Columns ahead the AI looks for spikes and pits to jump
+	aiFireLook    = 6.0                    // Columns ahead the AI waits for a fire vent to go out
+
+	demoRestartDelay = 3 * time.Second // How long a finished demo run stays up before the next
+)
+
+// aiPlayer decides which keys to press for a player each frame
+type aiPlayer struct {
+	rng        *rand.Rand // The AI's own randomness, so it doesn't change the run's spawns
+	target     float64    // X the AI is heading for when nothing needs fighting
+	perch      *Platform  // Platform it is heading for (nil for the ground)
+	retargetAt time.Time  // When to pick somewhere else to go
+}
+
+func newAIPlayer(seed int64) *aiPlayer {
+	return &aiPlayer{rng: rand.New(rand.NewSource(seed))}
+}
+
+// act presses this frame's keys for p
+func (a *aiPlayer) act(g *Game, p *Player) {
+	if p.Dead {
+		return
+	}
+	move, jump, throw := a.decide(g, p)
+	if move < 0 {
+		g.pressAction(p, ActionLeft)
+	} else if move > 0 {
+		g.pressAction(p, ActionRight)
+	}
+	if jump {
+		g.pressAction(p, ActionJump)
+	}
+	if throw {
+		g.pressAction(p, ActionThrow)
+	}
+}
+
+// decide picks the move direction and whether to jump and throw. Dodging comes
+// first, then fighting the nearest enemy, then getting around the level.
+func (a *aiPlayer) decide(g *Game, p *Player) (move int, jump, throw bool) {
+	jump = a.incoming(g, p)
+
+	if e := a.threat(g, p); e != nil {
+		dx := (e.Pos.X + float64(e.Width)/2) - (p.Pos.X + float64(p.Width)/2)
+		dir := 1
+		if dx < 0 {
+			dir = -1
+		}
+		inLine := inLineOfFire(p, e)
+		if inLine && p.Facing == dir {
+			return 0, jump, g.now.Sub(p.LastShot) > g.difficulty.ThrowCooldown
+		}
+		if inLine {
+			return dir, jump, false // Turn to face it
+		}
+		if math.Abs(dx) < aiKeepAway {
+			// Above or below, likely jumping: keep away until it comes into line
+			return -dir, jump, false
+		}
+	}
+
+	move, climb := a.navigate(g, p)
+	return move, jump || climb, false
+}
+
+// inLineOfFire reports whether a shuriken thrown by p would fly into e
+func inLineOfFire(p *Player, e *Enemy) bool {
+	y := p.Pos.Y + float64(p.Height/2)
+	return y < e.Pos.Y+float64(e.Height) && y+1 > e.Pos.Y
+}
+
+// incoming reports whether a shuriken that can hurt p is about to hit it
+func (a *aiPlayer) incoming(g *Game, p *Player) bool {
+	for i := range g.projectiles {
+		pr := &g.projectiles[i]
+		if !pr.Active || !g.hurts(pr, p) {
+			continue
+		}
+		vel := pr.velocity()
+		dx := p.Pos.X + float64(p.Width)/2 - pr.Pos.X
+		if dx*vel.X < 0 {
+			continue // Flying away
+		}
+		t := dx / vel.X
+		if y := pr.Pos.Y + vel.Y*t + pr.Gravity*t*t/2; y < p.Pos.Y || y >= p.Pos.Y+float64(p.Height) {
+			continue // Flying past above or below
+		}
+		if time.Duration(t*float64(time.Second)) < aiDodgeTime {
+			return true
+		}
+	}
+	return false
+}
+
+// threat is the nearest enemy worth fighting, or nil
+func (a *aiPlayer) threat(g *Game, p *Player) *Enemy {
+	var nearest *Enemy
+	best := aiThreatRange
+	for i := range g.enemies {
+		e := &g.enemies[i]
+		if !e.Active || math.Abs(e.Pos.Y-p.Pos.Y) > aiThreatRise {
+			continue
+		}
+		if d := math.Abs(e.Pos.X - p.Pos.X); d < best {
+			nearest, best = e, d
+		}
+	}
+	return nearest
+}
+
+// navigate heads for the stage goal, or on arenas for a spot picked every few
+// seconds, often up on a platform. It jumps spikes and pits and waits out
+// fire vents on the way.
+func (a *aiPlayer) navigate(g *Game, p *Player) (move int, jump bool) {
+	if g.goalX > 0 {
+		a.target, a.perch = g.goalX, nil
+	} else if !g.now.Before(a.retargetAt) {
+		a.pickTarget(g)
+	}
+
+	x := p.Pos.X + float64(p.Width)/2
+	dx := a.target - x
+	if math.Abs(dx) > aiArrived {
+		move = 1
+		if dx < 0 {
+			move = -1
+		}
+	}
+	standing := p.OnGround || p.OnPlatform
+	feet := p.Pos.Y + float64(p.Height)
+
+	if a.perch != nil && standing && math.Abs(feet-a.perch.Y) < 0.5 &&
+		p.Pos.X+float64(p.Width) > a.perch.X && p.Pos.X < a.perch.X+a.perch.Width {
+		move = 0 // Up there already
+	}
+
+	// Up onto the platform it's heading for, once a jump from here lands on it
+	if a.perch != nil && standing && feet > a.perch.Y+0.5 {
+		here := Platform{X: p.Pos.X, Y: p.Pos.Y + float64(p.Height), Width: 0}
+		if g.playerModel().canJump(here, *a.perch, 0) {
+			jump = true
+		}
+		// Make for the near end of it
+		if p.Pos.X+float64(p.Width) <= a.perch.X {
+			move = 1
+		} else if p.Pos.X >= a.perch.X+a.perch.Width {
+			move = -1
+		}
+	}
+
+	if move != 0 {
+		lo, hi := p.Pos.X+float64(p.Width), p.Pos.X+float64(p.Width)+aiHazardLook
+		fireLo, fireHi := lo, p.Pos.X+float64(p.Width)+aiFireLook
+		if move < 0 {
+			lo, hi = p.Pos.X-aiHazardLook, p.Pos.X
+			fireLo, fireHi = p.Pos.X-aiFireLook, hi
+		}
+		for _, h := range g.hazards {
+			switch h.Kind {
+			case HazardSpikes, HazardPit:
+				if h.X < hi && h.X+h.Width > lo && math.Abs(h.Y-feet) < 2 && standing {
+					jump = true
+				}
+			case HazardFire:
+				burning, warning := g.fireState(h)
+				if (burning || warning) && h.X < fireHi && h.X+h.Width > fireLo && math.Abs(h.Y-feet) < 2 {
+					move = 0 // Wait for it to go out
+				}
+			}
+		}
+	}
+	return move, jump
+}
+
+// pickTarget chooses somewhere new to go on an arena: half the time a
+// platform to stand on, otherwise a spot on the ground
+func (a *aiPlayer) pickTarget(g *Game) {
+	a.retargetAt = g.now.Add(time.Duration(3000+a.rng.Intn(3000)) * time.Millisecond)
+	a.perch = nil
+	if len(g.platforms) > 0 && a.rng.Float64() < 0.5 {
+		pl := g.platforms[a.rng.Intn(len(g.platforms))]
+		a.perch = &pl
+		a.target = pl.X + pl.Width/2
+		return
+	}
+	a.target = float64(PlayerWidth) + a.rng.Float64()*float64(g.worldWidth-2*PlayerWidth)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// updateCamera scrolls the view to follow the players through levels wider
+// than the screen. The players can move freely in the middle third of the
+// screen before the camera starts to follow; in co-op it follows the point
+// between them and never scrolls anyone off screen.
+func (g *Game) updateCamera() {
+	left, right, ok := g.playerSpan()
+	if !ok {
+		return
+	}
+
+	deadZone := g.width / 3
+	playerX := (left + right) / 2
+
+	if playerX-g.cameraX < deadZone {
+		g.cameraX = playerX - deadZone
+	}
+	if playerX-g.cameraX > g.width-deadZone {
+		g.cameraX = playerX - (g.width - deadZone)
+	}
+	if len(g.players) > 1 {
+		if g.cameraX > left {
+			g.cameraX = left
+		}
+		if g.cameraX+g.width < right {
+			g.cameraX = right - g.width
+		}
+	}
+	g.clampCamera()
+}
+
+// playerSpan is the range of world columns covered by the players still
+// standing (or every player, if nobody is)
+func (g *Game) playerSpan() (left, right int, ok bool) {
+	for pass := 0; pass < 2 && !ok; pass++ {
+		for i := range g.players {
+			p := &g.players[i]
+			if p.Dead && pass == 0 {
+				continue
+			}
+			l, r := int(p.Pos.X), int(p.Pos.X)+p.Width
+			if !ok || l < left {
+				left = l
+			}
+			if !ok || r > right {
+				right = r
+			}
+			ok = true
+		}
+	}
+	return left, right, ok
+}
+
+// clampCamera keeps the view inside the world
+func (g *Game) clampCamera() {
+	if g.cameraX > g.worldWidth-g.width {
+		g.cameraX = g.worldWidth - g.width
+	}
+	if g.cameraX < 0 {
+		g.cameraX = 0
+	}
+}
+
+// inView reports whether a world X position is on screen, or within margin
+// columns of it
+func (g *Game) inView(x float64, margin float64) bool {
+	return x >= float64(g.cameraX)-margin && x < float64(g.cameraX+g.width)+margin
+}
+
+// reachedGoal reports whether a player has made it to the end of a stage
+func (g *Game) reachedGoal() bool {
+	if g.goalX <= 0 {
+		return false
+	}
+	for i := range g.players {
+		if p := &g.players[i]; !p.Dead && p.Pos.X+float64(p.Width) >= g.goalX {
+			return true
+		}
+	}
+	return false
+}
+
+// drawGoal draws the finish flag of a stage (versus matches ignore it)
+func (g *Game) drawGoal() {
+	if g.goalX <= 0 || g.match != nil {
+		return
+	}
+	x := int(math.Round(g.goalX)) - g.cameraX
+	if x < 0 || x >= g.width {
+		return
+	}
+	poleStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	flagStyle := tcell.StyleDefault.Foreground(tcell.ColorGreen)
+	for y := g.groundY - 6; y < g.groundY; y++ {
+		g.screen.SetContent(x, y, '|', nil, poleStyle)
+	}
+	g.screen.SetContent(x+1, g.groundY-6, '>', nil, flagStyle)
+	g.screen.SetContent(x+1, g.groundY-5, '>', nil, flagStyle)
+}
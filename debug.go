@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// debugLookahead is how far ahead velocity vectors point on the debug overlay
+const debugLookahead = 0.25 // seconds
+
+// drawDebug draws the debug overlay (F3): the boxes checkCollisions tests,
+// where things are heading, and under the score what the director is doing
+// and how busy the frame is
+func (g *Game) drawDebug() {
+	for _, pl := range g.platforms {
+		g.debugBox(pl.X, pl.Y, pl.Width, math.Max(pl.Height, 1), tcell.ColorDarkBlue)
+	}
+	for i := range g.projectiles {
+		pr := &g.projectiles[i]
+		if !pr.Active {
+			continue
+		}
+		color := tcell.ColorDarkGreen
+		if pr.Owner == ownerEnemy {
+			color = tcell.ColorDarkMagenta
+		}
+		g.debugSweep(pr, color)
+	}
+	for i := range g.enemies {
+		e := &g.enemies[i]
+		if !e.Active {
+			continue
+		}
+		g.debugBox(e.Pos.X, e.Pos.Y, float64(e.Width), float64(e.Height), tcell.ColorDarkRed)
+		g.debugVector(e.Pos, e.Width, e.Height, e.Vel)
+		label := ""
+		if e.OnGround {
+			label = "G"
+		}
+		if e.CanShoot {
+			label += " " + g.shotTimer(e)
+		}
+		g.debugLabel(e.Pos, label)
+	}
+	for i := range g.players {
+		p := &g.players[i]
+		if p.Dead {
+			continue
+		}
+		g.debugBox(p.Pos.X, p.Pos.Y, float64(p.Width), float64(p.Height), tcell.ColorDarkGreen)
+		g.debugVector(p.Pos, p.Width, p.Height, p.Vel)
+		g.debugLabel(p.Pos, footing(p))
+	}
+
+	style := tcell.StyleDefault.Foreground(tcell.ColorDarkCyan)
+	lines := g.director.debugLines(g.now)
+	if g.frameTime > 0 {
+		lines = append(lines, fmt.Sprintf("frame %.1fms (%.0f fps)", g.frameTime.Seconds()*1000, 1/g.frameTime.Seconds()))
+	}
+	lines = append(lines,
+		fmt.Sprintf("players %d, enemies %d, projectiles %d, corpses %d",
+			len(g.players), len(g.enemies), len(g.projectiles), len(g.corpses)),
+		fmt.Sprintf("particles: death %d, blood %d", len(g.deathParticles), len(g.bloodParticles)),
+	)
+	for i := range g.players {
+		p := &g.players[i]
+		if p.Dead {
+			lines = append(lines, fmt.Sprintf("p%d down", i+1))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("p%d x %.2f-%.2f y %.2f-%.2f vel (%.1f, %.1f) %s",
+			i+1, p.Pos.X, p.Pos.X+float64(p.Width), p.Pos.Y, p.Pos.Y+float64(p.Height), p.Vel.X, p.Vel.Y, footing(p)),
+			"   "+g.closest(p))
+	}
+	for i, line := range lines {
+		g.drawText(0, 2+i, line, style)
+	}
+}
+
+// closest says how far a player's box is from the nearest enemy's and the
+// nearest shot that can hurt them: how close a call was, or whether it was
+// a hit at all
+func (g *Game) closest(p *Player) string {
+	w, h := float64(p.Width), float64(p.Height)
+	enemy, shot := math.Inf(1), math.Inf(1)
+	for i := range g.enemies {
+		if e := &g.enemies[i]; e.Active {
+			enemy = math.Min(enemy, boxGap(p.Pos, w, h, e.Pos, float64(e.Width), float64(e.Height)))
+		}
+	}
+	for i := range g.projectiles {
+		if pr := &g.projectiles[i]; pr.Active && g.hurts(pr, p) {
+			shot = math.Min(shot, boxGap(p.Pos, w, h, pr.Pos, 1, 1))
+		}
+	}
+	return fmt.Sprintf("gap to nearest enemy %s, shot %s", gapText(enemy), gapText(shot))
+}
+
+// gapText writes a gap, or "-" if there was nothing to measure to
+func gapText(gap float64) string {
+	if math.IsInf(gap, 1) {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", gap)
+}
+
+// boxGap is how far apart two boxes are, along whichever axis they're
+// furthest apart on. They touch when it's below 0.
+func boxGap(a Vec2, aw, ah float64, b Vec2, bw, bh float64) float64 {
+	x := math.Max(b.X-(a.X+aw), a.X-(b.X+bw))
+	y := math.Max(b.Y-(a.Y+ah), a.Y-(b.Y+bh))
+	return math.Max(x, y)
+}
+
+// footing says what a player is standing on
+func footing(p *Player) string {
+	switch {
+	case p.OnPlatform:
+		return "P"
+	case p.OnGround:
+		return "G"
+	}
+	return "air"
+}
+
+// shotTimer says when a shooter fires next: mid-burst, a countdown, or
+// "hold" once it's waiting for a line
+func (g *Game) shotTimer(e *Enemy) string {
+	switch {
+	case e.LastShot.IsZero():
+		return "-"
+	case e.Burst > 0:
+		return fmt.Sprintf("burst %d", e.Burst)
+	}
+	left := e.NextShotDelay - g.now.Sub(e.LastShot)
+	if left <= 0 {
+		return "hold"
+	}
+	return fmt.Sprintf("%.1fs", left.Seconds())
+}
+
+// debugBox shades the cells a box in world space covers, keeping what's
+// drawn in them
+func (g *Game) debugBox(x, y, w, h float64, color tcell.Color) {
+	for cy := int(math.Floor(y)); cy < int(math.Ceil(y+h)); cy++ {
+		for cx := int(math.Floor(x)); cx < int(math.Ceil(x+w)); cx++ {
+			g.debugShade(cx-g.cameraX, cy, color)
+		}
+	}
+}
+
+// debugSweep shades the points projectileHits checks along a projectile's
+// path this frame
+func (g *Game) debugSweep(pr *Projectile, color tcell.Color) {
+	for k := 0; k <= projectileSweepSteps; k++ {
+		t := float64(k) / projectileSweepSteps
+		x := pr.PrevPos.X + (pr.Pos.X-pr.PrevPos.X)*t
+		y := pr.PrevPos.Y + (pr.Pos.Y-pr.PrevPos.Y)*t
+		g.debugBox(x, y, 1, 1, color)
+	}
+}
+
+// debugShade sets the background of a screen cell
+func (g *Game) debugShade(x, y int, color tcell.Color) {
+	if x < 0 || x >= g.width || y < 0 || y >= g.height {
+		return
+	}
+	r, comb, style, _ := g.screen.GetContent(x, y)
+	g.screen.SetContent(x, y, r, comb, style.Background(color))
+}
+
+// debugVector draws where a body's velocity takes it over the next
+// debugLookahead seconds, from its middle, in the empty cells on the way
+func (g *Game) debugVector(pos Vec2, width, height int, vel Vec2) {
+	x0, y0 := pos.X+float64(width)/2, pos.Y+float64(height)/2
+	dx, dy := vel.X*debugLookahead, vel.Y*debugLookahead
+	n := int(math.Max(math.Abs(dx), math.Abs(dy)))
+	style := tcell.StyleDefault.Foreground(tcell.ColorYellow)
+	for k := 1; k <= n; k++ {
+		t := float64(k) / float64(n)
+		x, y := int(x0+dx*t)-g.cameraX, int(y0+dy*t)
+		if x < 0 || x >= g.width || y < 0 || y >= g.height {
+			continue
+		}
+		if r, _, _, _ := g.screen.GetContent(x, y); r != ' ' && r != 0 {
+			continue
+		}
+		ch := '·'
+		if k == n {
+			ch = '+'
+		}
+		g.screen.SetContent(x, y, ch, nil, style)
+	}
+}
+
+// debugLabel writes a note just above a body
+func (g *Game) debugLabel(pos Vec2, text string) {
+	y := int(pos.Y) - 1
+	if text == "" || y < 0 {
+		return
+	}
+	g.drawText(int(pos.X)-g.cameraX, y, text, tcell.StyleDefault.Foreground(tcell.ColorYellow))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Difficulty is a preset of how hard a run plays
+type Difficulty struct {
+	Name            string
+	Accuracy        float64       // How well shooters aim, from 0 (wildly, not leading at all) to 1 (dead on)
+	EnemySpeed      float64       // How fast enemies walk, and steer in the air
+	ShotSpeed       float64       // How fast enemy shots fly (lobbed ones excepted)
+	MinShotDelay    time.Duration // Shortest wait between a shooter's volleys
+	MaxShotDelay    time.Duration // Longest wait between a shooter's volleys
+	ReliefSpawnRate float64       // Chance of an enemy spawning each frame while the director eases off
+	BaseSpawnRate   float64       // Chance of an enemy spawning each frame at the start of a build-up
+	MaxSpawnRate    float64       // Highest the chance goes at the peak of a build-up
+	BuildTime       time.Duration // How long a build-up takes to reach its peak
+	ThrowCooldown   time.Duration // Shortest time between a player's shuriken
+	Lives           int           // Lives in a solo run
+	CoopLives       int           // Lives per player in co-op
+}
+
+// difficulties are the presets that can be picked on the title screen,
+// easiest first
+var difficulties = []Difficulty{
+	{
+		Name: "Easy", Accuracy: 0.4, EnemySpeed: 16, ShotSpeed: 60,
+		MinShotDelay: 2 * time.Second, MaxShotDelay: 4 * time.Second,
+		ReliefSpawnRate: 0.003, BaseSpawnRate: 0.006, MaxSpawnRate: 0.03, BuildTime: 25 * time.Second,
+		ThrowCooldown: 150 * time.Millisecond, Lives: 3, CoopLives: 5,
+	},
+	{
+		Name: "Normal", Accuracy: 0.7, EnemySpeed: 20, ShotSpeed: enemyProjectileSpeed,
+		MinShotDelay: time.Second, MaxShotDelay: 3 * time.Second,
+		ReliefSpawnRate: 0.004, BaseSpawnRate: 0.008, MaxSpawnRate: 0.05, BuildTime: 20 * time.Second,
+		ThrowCooldown: 200 * time.Millisecond, Lives: 1, CoopLives: 3,
+	},
+	{
+		Name: "Hard", Accuracy: 0.85, EnemySpeed: 24, ShotSpeed: 100,
+		MinShotDelay: 800 * time.Millisecond, MaxShotDelay: 2 * time.Second,
+		ReliefSpawnRate: 0.006, BaseSpawnRate: 0.012, MaxSpawnRate: 0.07, BuildTime: 16 * time.Second,
+		ThrowCooldown: 250 * time.Millisecond, Lives: 1, CoopLives: 2,
+	},
+	{
+		Name: "Nightmare", Accuracy: 1, EnemySpeed: 28, ShotSpeed: 120,
+		MinShotDelay: 500 * time.Millisecond, MaxShotDelay: 1500 * time.Millisecond,
+		ReliefSpawnRate: 0.01, BaseSpawnRate: 0.018, MaxSpawnRate: 0.09, BuildTime: 12 * time.Second,
+		ThrowCooldown: 300 * time.Millisecond, Lives: 1, CoopLives: 1,
+	},
+}
+
+// normalDifficulty is what a game plays at until another is picked
+var normalDifficulty = difficulties[1]
+
+// difficultyNamed returns the preset with a name, or Normal and false if
+// there isn't one
+func difficultyNamed(name string) (Difficulty, bool) {
+	for _, d := range difficulties {
+		if strings.EqualFold(d.Name, name) {
+			return d, true
+		}
+	}
+	return normalDifficulty, false
+}
+
+// nextDifficulty is the preset after d, going round to Easy after Nightmare
+func nextDifficulty(d Difficulty) Difficulty {
+	for i := range difficulties {
+		if difficulties[i].Name == d.Name {
+			return difficulties[(i+1)%len(difficulties)]
+		}
+	}
+	return normalDifficulty
+}
+
+// shotDelay picks how long a shooter waits before its next volley
+func (g *Game) shotDelay() time.Duration {
+	d := &g.difficulty
+	return d.MinShotDelay + time.Duration(g.rng.Float64()*float64(d.MaxShotDelay-d.MinShotDelay))
+}
+
+// setDifficulty picks the preset new runs play at and remembers it for the
+// player
+func (g *Game) setDifficulty(d Difficulty) {
+	g.difficulty = d
+	if g.settings != nil {
+		s := g.settings.Get(g.playerName)
+		s.Difficulty = d.Name
+		if err := g.settings.Set(g.playerName, s); err != nil {
+			g.menuNote = "Settings not saved: " + err.Error()
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// The director paces a run. Rather than the spawn rate climbing steadily to
+// its cap, it builds the pressure up, holds a peak, then eases off for a
+// while before building again, and eases off straight away whenever a
+// player is hit. How high a build-up goes depends on how the players are
+// doing: recent kills and a long time since anyone was hit push it higher,
+// and near misses bring the peak on sooner.
+
+// Phases the director goes through
+const (
+	phaseBuild = iota
+	phasePeak
+	phaseRelief
+)
+
+var phaseNames = [...]string{"build-up", "peak", "relief"}
+
+const (
+	peakTime   = 6 * time.Second
+	reliefTime = 6 * time.Second
+
+	directorWindow = 15 * time.Second // How far back kills and near misses count
+	skillKills     = 8                // Kills in the window for the players to be doing as well as can be
+	stressMisses   = 4                // Near misses in the window that bring the peak on
+	calmTime       = 45 * time.Second // Time without a hit for the players to be fully settled
+	nearMiss       = 2.0              // How close a shot can pass a player for a near miss
+
+	minShooters  = 0.2 // Share of new enemies that shoot, while easing off
+	maxShooters  = 0.6 // Share of new enemies that shoot, at the end of a build-up
+	shooterShare = 0.4 // Share of shooters a level's spawn table is taken to be written for
+)
+
+// director is the pacing of a run. It's saved with a run in progress.
+type director struct {
+	Phase      int         `json:"phase"`
+	Since      time.Time   `json:"since"`      // When the phase started
+	LastHit    time.Time   `json:"lastHit"`    // When a player was last hit (the start of the run if nobody has been)
+	Kills      []time.Time `json:"kills"`      // Kills by players, within the window
+	NearMisses []time.Time `json:"nearMisses"` // Within the window
+	Rate       float64     `json:"rate"`       // Chance of an enemy spawning each frame
+	Shooters   float64     `json:"shooters"`   // Share of new enemies that shoot
+
+	// How the players are doing, from 0 to 1, as of the last frame
+	Skill  float64 `json:"skill"`  // Recent kills
+	Calm   float64 `json:"calm"`   // Time since the last hit
+	Stress float64 `json:"stress"` // Recent near misses
+}
+
+// resetDirector starts pacing a run from a build-up
+func (g *Game) resetDirector() {
+	g.director = director{LastHit: g.now}
+	g.enterPhase(phaseBuild)
+}
+
+// enterPhase starts one of the director's phases
+func (g *Game) enterPhase(phase int) {
+	d := &g.director
+	d.Phase, d.Since = phase, g.now
+	switch phase {
+	case phaseBuild:
+		d.Rate, d.Shooters = g.difficulty.BaseSpawnRate, minShooters
+	case phaseRelief:
+		d.Rate, d.Shooters = g.difficulty.ReliefSpawnRate, minShooters
+	}
+}
+
+// directorEvent notes kills and hits for the director
+func (g *Game) directorEvent(ev Event) {
+	switch ev := ev.(type) {
+	case EnemyKilled:
+		if ev.By >= 0 {
+			g.director.Kills = append(g.director.Kills, g.now)
+		}
+	case PlayerHit:
+		g.director.LastHit = g.now
+		g.enterPhase(phaseRelief)
+	}
+}
+
+// direct moves the director on a frame and returns the spawn rate it wants
+func (g *Game) direct() float64 {
+	d := &g.director
+	now := g.now
+	g.noteNearMisses()
+	d.Kills = within(d.Kills, now.Add(-directorWindow))
+	d.NearMisses = within(d.NearMisses, now.Add(-directorWindow))
+	d.Skill = math.Min(1, float64(len(d.Kills))/skillKills)
+	d.Stress = math.Min(1, float64(len(d.NearMisses))/stressMisses)
+	d.Calm = math.Min(1, now.Sub(d.LastHit).Seconds()/calmTime.Seconds())
+
+	in := now.Sub(d.Since)
+	switch d.Phase {
+	case phaseBuild:
+		base := g.difficulty.BaseSpawnRate
+		progress := math.Min(1, in.Seconds()/g.difficulty.BuildTime.Seconds())
+		peak := base + (g.difficulty.MaxSpawnRate-base)*(d.Skill+d.Calm)/2
+		d.Rate = base + (peak-base)*progress
+		d.Shooters = minShooters + (maxShooters-minShooters)*progress
+		if progress >= 1 || d.Stress >= 1 {
+			g.enterPhase(phasePeak)
+		}
+	case phasePeak:
+		if in >= peakTime {
+			g.enterPhase(phaseRelief)
+		}
+	case phaseRelief:
+		if in >= reliefTime && d.Stress < 0.5 {
+			g.enterPhase(phaseBuild)
+		}
+	}
+
+	// Rounded, so the rate doesn't change every frame
+	return math.Round(d.Rate*1000) / 1000
+}
+
+// noteNearMisses counts enemy shots that passed close by a player this frame
+func (g *Game) noteNearMisses() {
+	for i := range g.projectiles {
+		pr := &g.projectiles[i]
+		if !pr.Active || pr.Owner != ownerEnemy {
+			continue
+		}
+		for j := range g.players {
+			p := &g.players[j]
+			x := p.Pos.X + float64(p.Width)/2
+			if p.Dead || (pr.PrevPos.X-x)*(pr.Pos.X-x) > 0 {
+				continue // Hasn't passed them
+			}
+			above := p.Pos.Y - (pr.Pos.Y + 1)
+			below := pr.Pos.Y - (p.Pos.Y + float64(p.Height))
+			if (above >= 0 && above < nearMiss) || (below >= 0 && below < nearMiss) {
+				g.director.NearMisses = append(g.director.NearMisses, g.now)
+			}
+		}
+	}
+}
+
+// within drops the times before since
+func within(times []time.Time, since time.Time) []time.Time {
+	for len(times) > 0 && times[0].Before(since) {
+		times = times[1:]
+	}
+	return times
+}
+
+// shooterWeight scales the weight of shooters in a level's spawn table to the
+// share the director wants
+func (d *director) shooterWeight(weight float64) float64 {
+	return weight * d.Shooters / shooterShare
+}
+
+// patterns are the shooter patterns new shooters may have: no spreads while
+// easing off
+func (d *director) patterns() []string {
+	if d.Phase == phaseRelief {
+		return []string{PatternBurst, PatternLob}
+	}
+	return shooterPatterns
+}
+
+// debugLines describe what the director is doing, for the debug overlay
+func (d *director) debugLines(now time.Time) []string {
+	return []string{
+		fmt.Sprintf("director: %s for %.1fs", phaseNames[d.Phase], now.Sub(d.Since).Seconds()),
+		fmt.Sprintf("spawn rate %.1f%%, shooters %.0f%%", d.Rate*100, d.Shooters*100),
+		fmt.Sprintf("kills %d, near misses %d (last %.0fs)", len(d.Kills), len(d.NearMisses), directorWindow.Seconds()),
+		fmt.Sprintf("last hit %.1fs ago", now.Sub(d.LastHit).Seconds()),
+		fmt.Sprintf("skill %.2f, calm %.2f, stress %.2f", d.Skill, d.Calm, d.Stress),
+	}
+}
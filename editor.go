@@ -0,0 +1,537 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Things the editor cursor can pick up
+const (
+	itemNone = iota
+	itemPlatform
+	itemHazard
+	itemEnemySpawn
+	itemPlayerSpawn
+)
+
+// Editor holds the state of the level editor. It edits the live layout
+// (g.platforms, g.hazards, g.enemySpawns, g.playerSpawn) directly so a
+// playtest runs exactly what's on screen.
+type Editor struct {
+	CursorX     int
+	CursorY     int
+	HeldKind    int // Item being carried with the cursor (itemNone if empty-handed)
+	HeldIndex   int
+	HazardKind  string // Kind placed by the hazard key
+	Playtesting bool
+	Name        string
+	Message     string
+	MessageTime time.Time
+	Naming      bool   // Typing the name to save the level under
+	NameInput   string // Name typed so far
+	Overwrite   string // Existing file the next ENTER replaces, once the player has been told
+}
+
+const maxLevelName = 30 // Characters in a level name typed in the editor
+
+var editorHelp = []string{
+	"Arrows: move  SPACE: pick up/drop  X: delete  [ ]: resize  F: flip spawn",
+	"P: platform  H: hazard  K: hazard kind  E: enemy spawn  N: player spawn",
+	"G: goal  - +: level width  T: playtest  S: save  O: open  C: clear  ESC: back",
+}
+
+// openEditor starts editing the level selected in the level menu
+func (g *Game) openEditor() {
+	g.projectiles = make([]Projectile, 0)
+	g.enemies = make([]Enemy, 0)
+	g.corpses = make([]Corpse, 0)
+	g.deathParticles = make([]DeathParticle, 0)
+	g.bloodParticles = make([]BloodParticle, 0)
+	g.redGroundTiles = make(map[int]int)
+
+	g.buildLevel()
+	g.resetPlayers()
+
+	g.editor = &Editor{
+		CursorX:    g.cameraX + g.width/2,
+		CursorY:    g.groundY - 1,
+		HazardKind: HazardSpikes,
+	}
+	g.editor.Name = g.level.Name
+	if g.level.Seed != 0 {
+		g.editor.Name = "Custom"
+	}
+	g.menuScreen = screenEditor
+}
+
+// closeEditor returns to the title screen with the selected level loaded
+func (g *Game) closeEditor() {
+	g.editor = nil
+	g.menuScreen = screenMain
+	g.buildLevel()
+	g.resetPlayers()
+	g.demo = nil // A new demo starts on the level
+}
+
+func (g *Game) startPlaytest() {
+	g.editor.Playtesting = true
+	g.editor.HeldKind = itemNone
+	g.inMenu = false
+	g.resetRun()
+}
+
+func (g *Game) stopPlaytest() {
+	g.editor.Playtesting = false
+	g.projectiles = make([]Projectile, 0)
+	g.enemies = make([]Enemy, 0)
+	g.corpses = make([]Corpse, 0)
+	g.deathParticles = make([]DeathParticle, 0)
+	g.bloodParticles = make([]BloodParticle, 0)
+	g.redGroundTiles = make(map[int]int)
+	g.redPlatformTiles = make(map[int]int)
+	g.gameOver = false
+	g.inMenu = true
+	g.resetPlayers()
+
+	// Back to where the cursor was
+	g.cameraX = g.editor.CursorX - g.width/2
+	g.clampCamera()
+}
+
+func (g *Game) editorMessage(format string, args ...interface{}) {
+	g.editor.Message = fmt.Sprintf(format, args...)
+	g.editor.MessageTime = time.Now()
+}
+
+// itemAt finds the item under a screen cell, preferring the smallest things
+// (spawns) over the large ones (platforms)
+func (g *Game) itemAt(x, y int) (int, int) {
+	if g.playerSpawn != nil {
+		px, py := int(g.playerSpawn.X), int(g.playerSpawn.Y)
+		if x >= px && x < px+PlayerWidth && y >= py && y < py+PlayerHeight {
+			return itemPlayerSpawn, 0
+		}
+	}
+	for i, s := range g.enemySpawns {
+		sx, sy := g.spawnMarkerPos(s)
+		if x == sx && y == sy {
+			return itemEnemySpawn, i
+		}
+	}
+	for i, h := range g.hazards {
+		hy := int(h.Y)
+		if h.Kind == HazardPit {
+			hy = g.groundY
+		}
+		if y == hy && x >= int(h.X) && x < int(h.X+h.Width) {
+			return itemHazard, i
+		}
+	}
+	for i, p := range g.platforms {
+		if y == int(p.Y) && x >= int(p.X) && x < int(p.X+p.Width) {
+			return itemPlatform, i
+		}
+	}
+	return itemNone, 0
+}
+
+// spawnMarkerPos is the world cell an enemy spawn point is drawn at; spawns
+// just off the edge of the world are pulled back into it
+func (g *Game) spawnMarkerPos(s SpawnPoint) (int, int) {
+	x := int(s.X) + EnemyWidth/2
+	if x < 0 {
+		x = 0
+	}
+	if x > g.worldWidth-1 {
+		x = g.worldWidth - 1
+	}
+	return x, int(s.Y)
+}
+
+// moveItem shifts an item by the cursor's movement
+func (g *Game) moveItem(kind, index, dx, dy int) {
+	switch kind {
+	case itemPlatform:
+		g.platforms[index].X += float64(dx)
+		g.platforms[index].Y += float64(dy)
+	case itemHazard:
+		g.hazards[index].X += float64(dx)
+		g.hazards[index].Y += float64(dy)
+	case itemEnemySpawn:
+		g.enemySpawns[index].X += float64(dx)
+		g.enemySpawns[index].Y += float64(dy)
+	case itemPlayerSpawn:
+		g.playerSpawn.X += float64(dx)
+		g.playerSpawn.Y += float64(dy)
+		g.resetPlayers()
+	}
+}
+
+// resizeItem grows or shrinks a platform or hazard, never below one tile
+func (g *Game) resizeItem(kind, index, delta int) {
+	switch kind {
+	case itemPlatform:
+		g.platforms[index].Width += float64(delta)
+		if g.platforms[index].Width < 1 {
+			g.platforms[index].Width = 1
+		}
+	case itemHazard:
+		g.hazards[index].Width += float64(delta)
+		if g.hazards[index].Width < 1 {
+			g.hazards[index].Width = 1
+		}
+	}
+}
+
+func (g *Game) deleteItem(kind, index int) {
+	switch kind {
+	case itemPlatform:
+		g.platforms = append(g.platforms[:index], g.platforms[index+1:]...)
+		g.redPlatformTiles = make(map[int]int)
+	case itemHazard:
+		g.hazards = append(g.hazards[:index], g.hazards[index+1:]...)
+	case itemEnemySpawn:
+		g.enemySpawns = append(g.enemySpawns[:index], g.enemySpawns[index+1:]...)
+	case itemPlayerSpawn:
+		g.playerSpawn = nil
+		g.resetPlayers()
+	}
+}
+
+func (g *Game) handleEditorInput(ev *tcell.EventKey) {
+	ed := g.editor
+	if ed.Naming {
+		g.handleNameInput(ev)
+		return
+	}
+	dx, dy := 0, 0
+
+	switch ev.Key() {
+	case tcell.KeyLeft:
+		dx = -1
+	case tcell.KeyRight:
+		dx = 1
+	case tcell.KeyUp:
+		dy = -1
+	case tcell.KeyDown:
+		dy = 1
+	case tcell.KeyDelete, tcell.KeyBackspace, tcell.KeyBackspace2:
+		if kind, index := g.itemAt(ed.CursorX, ed.CursorY); kind != itemNone {
+			g.deleteItem(kind, index)
+			ed.HeldKind = itemNone
+		}
+	case tcell.KeyEscape:
+		g.closeEditor()
+		return
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case ' ':
+			if ed.HeldKind != itemNone {
+				ed.HeldKind = itemNone
+			} else {
+				ed.HeldKind, ed.HeldIndex = g.itemAt(ed.CursorX, ed.CursorY)
+			}
+		case 'x', 'X':
+			if kind, index := g.itemAt(ed.CursorX, ed.CursorY); kind != itemNone {
+				g.deleteItem(kind, index)
+				ed.HeldKind = itemNone
+			}
+		case '[', ']':
+			delta := 1
+			if ev.Rune() == '[' {
+				delta = -1
+			}
+			kind, index := ed.HeldKind, ed.HeldIndex
+			if kind == itemNone {
+				kind, index = g.itemAt(ed.CursorX, ed.CursorY)
+			}
+			g.resizeItem(kind, index, delta)
+		case 'f', 'F':
+			if kind, index := g.itemAt(ed.CursorX, ed.CursorY); kind == itemEnemySpawn {
+				// Cycle right -> left -> toward player
+				switch g.enemySpawns[index].Facing {
+				case 1:
+					g.enemySpawns[index].Facing = -1
+				case -1:
+					g.enemySpawns[index].Facing = 0
+				default:
+					g.enemySpawns[index].Facing = 1
+				}
+			}
+		case 'p', 'P':
+			g.platforms = append(g.platforms, Platform{
+				X:      float64(ed.CursorX),
+				Y:      float64(ed.CursorY),
+				Width:  10,
+				Height: 1,
+			})
+			ed.HeldKind, ed.HeldIndex = itemPlatform, len(g.platforms)-1
+		case 'h', 'H':
+			g.hazards = append(g.hazards, Hazard{
+				Kind:  ed.HazardKind,
+				X:     float64(ed.CursorX),
+				Y:     float64(ed.CursorY),
+				Width: 3,
+			})
+			ed.HeldKind, ed.HeldIndex = itemHazard, len(g.hazards)-1
+		case 'k', 'K':
+			switch ed.HazardKind {
+			case HazardSpikes:
+				ed.HazardKind = HazardFire
+			case HazardFire:
+				ed.HazardKind = HazardPit
+			default:
+				ed.HazardKind = HazardSpikes
+			}
+			// Change the kind of a hazard under the cursor too
+			if kind, index := g.itemAt(ed.CursorX, ed.CursorY); kind == itemHazard {
+				g.hazards[index].Kind = ed.HazardKind
+			}
+		case 'e', 'E':
+			g.enemySpawns = append(g.enemySpawns, SpawnPoint{
+				X: float64(ed.CursorX - EnemyWidth/2),
+				Y: float64(ed.CursorY),
+			})
+			ed.HeldKind, ed.HeldIndex = itemEnemySpawn, len(g.enemySpawns)-1
+		case 'n', 'N':
+			g.playerSpawn = &Vec2{X: float64(ed.CursorX), Y: float64(ed.CursorY)}
+			g.resetPlayers()
+			ed.HeldKind = itemPlayerSpawn
+		case 'g', 'G':
+			// Toggle the stage goal at the cursor
+			if g.goalX == float64(ed.CursorX) {
+				g.goalX = 0
+			} else {
+				g.goalX = float64(ed.CursorX)
+			}
+		case '-', '_':
+			if g.worldWidth-10 >= g.width && g.worldWidth-10 > ed.CursorX {
+				g.worldWidth -= 10
+				g.clampCamera()
+			}
+		case '+', '=':
+			g.worldWidth += 10
+		case 't', 'T':
+			g.startPlaytest()
+		case 's', 'S':
+			if g.levels[g.levelIndex].path != "" && g.levels[g.levelIndex].path == g.editedLevelPath(ed.Name) {
+				g.saveEdits() // Back to the file it came from
+			} else {
+				// Generated and built-in levels need a file of their own
+				ed.Naming, ed.NameInput, ed.Overwrite = true, ed.Name, ""
+			}
+		case 'o', 'O':
+			ed.HeldKind = itemNone
+			g.levelCursor = g.levelIndex
+			g.menuScreen = screenLevels
+		case 'c', 'C':
+			g.platforms = make([]Platform, 0)
+			g.hazards = make([]Hazard, 0)
+			g.enemySpawns = make([]SpawnPoint, 0)
+			g.playerSpawn = nil
+			g.redPlatformTiles = make(map[int]int)
+			g.resetPlayers()
+			ed.HeldKind = itemNone
+		}
+	}
+
+	if dx == 0 && dy == 0 {
+		return
+	}
+
+	// Move the cursor, dragging any held item along with it
+	newX := ed.CursorX + dx
+	newY := ed.CursorY + dy
+	if newX < 0 || newX >= g.worldWidth || newY < 0 || newY > g.groundY {
+		return
+	}
+	ed.CursorX, ed.CursorY = newX, newY
+	if ed.HeldKind != itemNone {
+		g.moveItem(ed.HeldKind, ed.HeldIndex, dx, dy)
+	}
+
+	// Scroll to keep the cursor a few columns inside the screen
+	if ed.CursorX < g.cameraX+4 {
+		g.cameraX = ed.CursorX - 4
+	}
+	if ed.CursorX >= g.cameraX+g.width-4 {
+		g.cameraX = ed.CursorX - g.width + 5
+	}
+	g.clampCamera()
+}
+
+// handleNameInput types the name a level is saved under. Saving over another
+// level's file takes a second ENTER.
+func (g *Game) handleNameInput(ev *tcell.EventKey) {
+	ed := g.editor
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		ed.Naming = false
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if n := len(ed.NameInput); n > 0 {
+			ed.NameInput = ed.NameInput[:n-1]
+			ed.Overwrite = ""
+		}
+	case tcell.KeyRune:
+		if len(ed.NameInput) < maxLevelName && ev.Rune() >= ' ' && ev.Rune() <= '~' {
+			ed.NameInput += string(ev.Rune())
+			ed.Overwrite = ""
+		}
+	case tcell.KeyEnter:
+		name := strings.TrimSpace(ed.NameInput)
+		if name == "" {
+			return
+		}
+		path := g.editedLevelPath(name)
+		if _, err := os.Stat(path); err == nil && path != ed.Overwrite && path != g.levels[g.levelIndex].path {
+			ed.Overwrite = path
+			return
+		}
+		ed.Name, ed.Naming = name, false
+		g.saveEdits()
+	}
+}
+
+// saveEdits saves the edited level and says how it went
+func (g *Game) saveEdits() {
+	path, err := g.saveEditedLevel()
+	if err != nil {
+		g.editorMessage("Save failed: %v", err)
+	} else {
+		g.editorMessage("Saved %s", path)
+	}
+}
+
+// editedLevelPath is the file a level called name is saved to ("" if there's
+// no config directory)
+func (g *Game) editedLevelPath(name string) string {
+	dir := userLevelDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, levelFileName(name))
+}
+
+// editedLevel captures the layout on screen as a level file authored for the
+// current terminal size
+func (g *Game) editedLevel() *Level {
+	lvl := &Level{
+		Name:        g.editor.Name,
+		Width:       g.worldWidth,
+		Height:      g.height,
+		Goal:        g.goalX,
+		Platforms:   append([]Platform(nil), g.platforms...),
+		Hazards:     append([]Hazard(nil), g.hazards...),
+		EnemySpawns: append([]SpawnPoint(nil), g.enemySpawns...),
+		SpawnTable:  g.level.SpawnTable,
+	}
+	if g.playerSpawn != nil {
+		spawn := *g.playerSpawn
+		lvl.PlayerSpawn = &spawn
+	}
+	return lvl
+}
+
+// saveEditedLevel writes the edited level to the user level directory and
+// selects it in the level menu
+func (g *Game) saveEditedLevel() (string, error) {
+	dir := userLevelDir()
+	if dir == "" {
+		return "", fmt.Errorf("no config directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	lvl := g.editedLevel()
+	data, err := json.MarshalIndent(lvl, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := g.editedLevelPath(lvl.Name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+
+	g.level = lvl
+	for i, source := range g.levels {
+		if source.path == path {
+			g.levelIndex = i
+			return path, nil
+		}
+	}
+	g.levels = append(g.levels, fileLevelSource(path, lvl))
+	g.levelIndex = len(g.levels) - 1
+	return path, nil
+}
+
+// levelFileName turns a level name into a safe file name
+func levelFileName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ', r == '-', r == '_':
+			b.WriteRune('-')
+		}
+	}
+	if b.Len() == 0 {
+		return "level.json"
+	}
+	return b.String() + ".json"
+}
+
+func (g *Game) drawEditor() {
+	ed := g.editor
+
+	// Enemy spawn points as arrows showing which way enemies will face
+	spawnStyle := tcell.StyleDefault.Foreground(tcell.ColorLightGray)
+	for _, s := range g.enemySpawns {
+		x, y := g.spawnMarkerPos(s)
+		char := 'E'
+		switch s.Facing {
+		case 1:
+			char = '>'
+		case -1:
+			char = '<'
+		}
+		g.screen.SetContent(x-g.cameraX, y, char, nil, spawnStyle)
+	}
+
+	// Help and status lines
+	helpStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	for row, line := range editorHelp {
+		for i, r := range line {
+			g.screen.SetContent(i, row, r, nil, helpStyle)
+		}
+	}
+	status := fmt.Sprintf("Editing: %s  Hazard: %s  Cursor: %d,%d  Width: %d", ed.Name, ed.HazardKind, ed.CursorX, ed.CursorY, g.worldWidth)
+	if ed.Message != "" && time.Since(ed.MessageTime) < 3*time.Second {
+		status = ed.Message
+	}
+	if ed.Naming {
+		status = "Save as: " + ed.NameInput + "_  (ENTER to save, ESC to cancel)"
+		if ed.Overwrite != "" {
+			status = fmt.Sprintf("%s already exists: ENTER again to replace it, or type another name", filepath.Base(ed.Overwrite))
+		}
+	}
+	greenStyle := tcell.StyleDefault.Foreground(tcell.ColorGreen)
+	for i, r := range status {
+		g.screen.SetContent(i, len(editorHelp), r, nil, greenStyle)
+	}
+
+	// Cursor: invert whatever is under it
+	cursorX := ed.CursorX - g.cameraX
+	mainc, combc, style, _ := g.screen.GetContent(cursorX, ed.CursorY)
+	if mainc == ' ' || mainc == 0 {
+		mainc = '+'
+	}
+	g.screen.SetContent(cursorX, ed.CursorY, mainc, combc, style.Reverse(true))
+}
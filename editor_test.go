@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// typeText types a string a key at a time
+func typeText(g *Game, text string) {
+	for _, r := range text {
+		pressKey(g, tcell.KeyRune, r)
+	}
+}
+
+func TestLevelSelectFromEditorStaysStill(t *testing.T) {
+	g := newTestGame(t)
+	pressKey(g, tcell.KeyRune, 'e')
+	pressKey(g, tcell.KeyRune, 'o')
+	player := g.players[0].Pos
+	for f := 0; f < 300; f++ {
+		g.update(1.0 / 30)
+	}
+	pressKey(g, tcell.KeyEscape, 0)
+	if g.editor == nil {
+		t.Fatal("ESC from the level select left the editor")
+	}
+	if len(g.enemies) > 0 || g.players[0].Pos != player {
+		t.Errorf("the demo played on the layout being edited: %d enemies, player moved from %v to %v", len(g.enemies), player, g.players[0].Pos)
+	}
+}
+
+func TestEditorAsksBeforeOverwriting(t *testing.T) {
+	g := newTestGame(t)
+	pressKey(g, tcell.KeyRune, 'e')
+	pressKey(g, tcell.KeyRune, 's')
+	if !g.editor.Naming {
+		t.Fatal("saving a generated level didn't ask for a name")
+	}
+	pressKey(g, tcell.KeyEnter, 0)
+	first := g.editedLevelPath("Custom")
+	if _, err := os.Stat(first); err != nil {
+		t.Fatal(err)
+	}
+	pressKey(g, tcell.KeyEscape, 0)
+
+	// Another generated level under the same name
+	g.levelIndex = 1
+	pressKey(g, tcell.KeyRune, 'e')
+	pressKey(g, tcell.KeyRune, 'p') // Something to tell the files apart by
+	pressKey(g, tcell.KeyRune, 's')
+	saved, _ := os.ReadFile(first)
+	pressKey(g, tcell.KeyEnter, 0)
+	if data, _ := os.ReadFile(first); string(data) != string(saved) {
+		t.Fatal("the first ENTER replaced the other level")
+	}
+	if g.editor.Overwrite != first {
+		t.Errorf("not warned about replacing %s", filepath.Base(first))
+	}
+
+	// Typing another name saves alongside it instead
+	for range "Custom" {
+		pressKey(g, tcell.KeyBackspace2, 0)
+	}
+	typeText(g, "Tower Two")
+	pressKey(g, tcell.KeyEnter, 0)
+	if _, err := os.Stat(g.editedLevelPath("Tower Two")); err != nil || g.editor.Naming {
+		t.Errorf("not saved under the new name: %v", err)
+	}
+	if data, _ := os.ReadFile(first); string(data) != string(saved) {
+		t.Error("saving under a new name replaced the other level")
+	}
+
+	// Once saved, S goes straight back to the same file
+	pressKey(g, tcell.KeyRune, 's')
+	if g.editor.Naming {
+		t.Error("asked for a name again for a level already saved")
+	}
+}
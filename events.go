@@ -0,0 +1,118 @@
+package main
+
+// The simulation publishes events as things happen in a run, and everything
+// that reacts to them (scoring, stats, achievements) subscribes rather than
+// being written into the collision and spawning code. Subscribers are called
+// in the order they subscribed, during the frame the event happened, so they
+// see and may change the game as it is at that moment.
+
+// Event is one of the event types below
+type Event interface {
+	event()
+}
+
+// EnemyKilled is published when an enemy dies
+type EnemyKilled struct {
+	EnemyID int
+	Kind    string // EnemyWalker or EnemyShooter
+	Pos     Vec2
+	Cause   string // causeShuriken, or the kind of hazard that got it
+	By      int    // Player credited with the kill (-1 if nobody)
+}
+
+// Decapitation is published, after its EnemyKilled, when an enemy's head
+// comes off
+type Decapitation struct {
+	EnemyID int
+	Pos     Vec2
+	By      int // Player credited with the kill (-1 if nobody)
+}
+
+// PlayerHit is published when a player is killed and loses a life
+type PlayerHit struct {
+	Player    int
+	Pos       Vec2
+	Cause     string // One of the causes in causeText
+	By        int    // Player whose shuriken it was in versus (-1 otherwise)
+	LivesLeft int
+}
+
+// ProjectileFired is published when a player or an enemy throws a shuriken
+type ProjectileFired struct {
+	Owner int // Player index, or ownerEnemy
+	Pos   Vec2
+	Dir   int
+}
+
+// EnemySpawned is published when an enemy enters the level
+type EnemySpawned struct {
+	EnemyID int
+	Kind    string
+	Pos     Vec2
+}
+
+// SpawnRateChanged is published when the chance of an enemy spawning each
+// frame changes, which it does as the director paces the run
+type SpawnRateChanged struct {
+	Rate            float64
+	Phase           string // What the director is doing (one of phaseNames)
+	EnemiesDefeated int
+}
+
+// RunStarted is published when a run begins, or a saved one is continued
+type RunStarted struct {
+	Level      string
+	Seed       int64 // Seed of the run's enemy spawns
+	Players    int
+	Versus     bool
+	Resumed    bool
+	Difficulty string
+}
+
+// RunEnded is published when a run is over
+type RunEnded struct {
+	Scores []int   // By player
+	Time   float64 // Seconds
+	Clear  bool
+}
+
+func (EnemyKilled) event()      {}
+func (Decapitation) event()     {}
+func (PlayerHit) event()        {}
+func (ProjectileFired) event()  {}
+func (EnemySpawned) event()     {}
+func (SpawnRateChanged) event() {}
+func (RunStarted) event()       {}
+func (RunEnded) event()         {}
+
+// eventBus hands published events to its subscribers
+type eventBus struct {
+	subscribers []func(Event)
+}
+
+// subscribe adds a function to be called with every event published
+func (b *eventBus) subscribe(fn func(Event)) {
+	b.subscribers = append(b.subscribers, fn)
+}
+
+// publish calls every subscriber with an event
+func (b *eventBus) publish(ev Event) {
+	for _, fn := range b.subscribers {
+		fn(ev)
+	}
+}
+
+// playerIndex is the index of a player, or -1 for nil
+func playerIndex(p *Player) int {
+	if p == nil {
+		return -1
+	}
+	return p.Index
+}
+
+// scoreEvent scores kills for the player credited with them
+func (g *Game) scoreEvent(ev Event) {
+	if ev, ok := ev.(EnemyKilled); ok && ev.By >= 0 && ev.By < len(g.players) {
+		g.players[ev.By].Score += 10
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Ghost racing: solo runs are recorded as the actions held each frame, and the
+// best run on each level layout is saved. Later runs on the same layout race a
+// dim ghost that replays it. The ghost only moves through the level; enemies,
+// shuriken and hazards ignore it. Runs racing a ghost reuse its seed, so the
+// same enemies spawn as on the ghost's run (as long as the player plays it
+// the same way).
+
+const (
+	ghostSyncFrames = FPS                     // Frames between position fixes in a recording
+	maxGhostFrames  = 10 * 60 * FPS           // Ten minutes; longer runs aren't recorded
+	ghostEndLinger  = 1500 * time.Millisecond // How long a ghost that didn't make it stays after its run ends
+	maxGhosts       = 50                      // Best runs kept; the layouts played longest ago go first
+)
+
+// ghostFrame is one frame of a recorded run
+type ghostFrame struct {
+	DT   float64    `json:"dt"`             // Frame time in seconds
+	Held uint8      `json:"held,omitempty"` // Bit per Action held during the frame
+	Sync *ghostSync `json:"sync,omitempty"` // Where the player ended up, every ghostSyncFrames
+}
+
+// ghostSync pulls a replay back on course if it drifts from the recording
+type ghostSync struct {
+	X  float64 `json:"x"`
+	Y  float64 `json:"y"`
+	VY float64 `json:"vy"`
+}
+
+// Recording is a recorded run
+type Recording struct {
+	Level  string       `json:"level"`
+	Seed   int64        `json:"seed"` // Seed of the run's enemy spawns
+	Score  int          `json:"score"`
+	Clear  bool         `json:"clear,omitempty"` // Reached the stage goal
+	Time   float64      `json:"time"`            // Length of the run in seconds
+	Frames []ghostFrame `json:"frames"`
+}
+
+// beats reports whether a run is better than another: clearing a stage beats
+// not clearing it, then faster clears and higher scores win
+func (r *Recording) beats(other *Recording) bool {
+	if other == nil {
+		return true
+	}
+	if r.Clear != other.Clear {
+		return r.Clear
+	}
+	if r.Clear {
+		return r.Time < other.Time
+	}
+	if r.Score != other.Score {
+		return r.Score > other.Score
+	}
+	return r.Time > other.Time
+}
+
+// Ghost replays a recording alongside the live run
+type Ghost struct {
+	Run     *Recording
+	Player  Player  // The ghost's ninja, moved by the same code as the real one
+	Frame   int     // Next frame of the recording to play
+	Elapsed float64 // Seconds of the live run so far
+	Played  float64 // Seconds of the recording played so far
+	EndedAt time.Time
+}
+
+// heldActions packs the actions a player is holding into a bit set
+func (p *Player) heldActions(now time.Time) uint8 {
+	var held uint8
+	for action := Action(0); action < numActions; action++ {
+		if p.holding(action, now) {
+			held |= 1 << action
+		}
+	}
+	return held
+}
+
+// racesGhosts reports whether the current run is recorded and raced against
+// a ghost: solo runs only, outside the editor, the menu demo and network games,
+// and only on a layout that can be played again. A random level started
+// without --seed never comes round again, so there's no point keeping its runs.
+func (g *Game) racesGhosts() bool {
+	return g.ghostsOn && g.ghostDir != "" && g.numPlayers == 1 && g.match == nil && g.editor == nil &&
+		g.client == nil && !g.inMenu && (g.level.Seed == 0 || g.levelSeed != 0)
+}
+
+// ghostDir is where the best runs are kept
+func ghostDir() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "gninja", "ghosts")
+}
+
+// ghostPath is the file holding the best run on the current layout. Generated
+// layouts depend on the seed and the screen size, so both are part of the name,
+// and so is the difficulty unless it's Normal.
+func (g *Game) ghostPath() string {
+	if g.ghostDir == "" {
+		return ""
+	}
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, g.level.Name)
+	if g.difficulty.Name != normalDifficulty.Name {
+		name += "-" + strings.ToLower(g.difficulty.Name)
+	}
+	file := fmt.Sprintf("%s-%d-%dx%d.json", name, g.level.Seed, g.worldWidth, g.height)
+	return filepath.Join(g.ghostDir, file)
+}
+
+// loadRecording reads a saved run, or returns nil if there isn't one
+func loadRecording(path string) *Recording {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var r Recording
+	if err := json.Unmarshal(data, &r); err != nil || len(r.Frames) == 0 {
+		return nil
+	}
+	return &r
+}
+
+func (r *Recording) save(path string) error {
+	if path == "" {
+		return fmt.Errorf("no config directory to save the run in")
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	pruneGhosts(filepath.Dir(path))
+	return nil
+}
+
+// pruneGhosts deletes the runs on the layouts played longest ago once there
+// are more than maxGhosts
+func pruneGhosts(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type ghostFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []ghostFile
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			files = append(files, ghostFile{filepath.Join(dir, e.Name()), info.ModTime()})
+		}
+	}
+	if len(files) <= maxGhosts {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	for _, f := range files[maxGhosts:] {
+		os.Remove(f.path)
+	}
+}
+
+// startGhostRun seeds a new run's enemy spawns, loads the ghost to race and
+// starts recording
+func (g *Game) startGhostRun() {
+	g.recording, g.ghost, g.newBestRun = nil, nil, false
+
+	seed := g.levelSeed
+	if g.racesGhosts() {
+		if best := loadRecording(g.ghostPath()); best != nil {
+			now := time.Now()
+			os.Chtimes(g.ghostPath(), now, now) // Played again, so kept longer
+			ghost := g.newPlayer(0, g.spawnPos(0), 1)
+			g.ghost = &Ghost{Run: best, Player: ghost}
+			seed = best.Seed
+		}
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	g.seedRNG(seed, 0)
+
+	if g.racesGhosts() {
+		g.recording = &Recording{Level: g.level.Name, Seed: seed}
+	}
+}
+
+// recordFrame adds a frame to the run being recorded
+func (g *Game) recordFrame(deltaTime float64, held uint8) {
+	r := g.recording
+	if r == nil || len(r.Frames) >= maxGhostFrames {
+		return
+	}
+	frame := ghostFrame{DT: deltaTime, Held: held}
+	if len(r.Frames)%ghostSyncFrames == ghostSyncFrames-1 {
+		p := &g.players[0]
+		frame.Sync = &ghostSync{X: p.Pos.X, Y: p.Pos.Y, VY: p.Vel.Y}
+	}
+	r.Frames = append(r.Frames, frame)
+}
+
+// finishRecording saves the run that just ended if it's the best yet
+func (g *Game) finishRecording() {
+	r := g.recording
+	if r == nil || len(r.Frames) >= maxGhostFrames {
+		return
+	}
+	r.Score = g.players[0].Score
+	r.Clear = g.stageClear
+	r.Time = g.runTime.Seconds()
+
+	var best *Recording
+	if g.ghost != nil {
+		best = g.ghost.Run
+	}
+	if !r.beats(best) {
+		return
+	}
+	if err := r.save(g.ghostPath()); err != nil {
+		g.toast("Best run not saved: " + err.Error())
+		return
+	}
+	g.newBestRun = true
+}
+
+// updateGhost plays the ghost's recording up to the time the live run has
+// reached
+func (g *Game) updateGhost(deltaTime float64) {
+	gh := g.ghost
+	if gh == nil {
+		return
+	}
+	gh.Elapsed += deltaTime
+	for gh.Frame < len(gh.Run.Frames) {
+		f := gh.Run.Frames[gh.Frame]
+		if gh.Played+f.DT > gh.Elapsed {
+			break
+		}
+		now := g.now
+		for action := Action(0); action < numActions; action++ {
+			gh.Player.Pressed[action] = time.Time{}
+			if f.Held&(1<<action) != 0 {
+				gh.Player.Pressed[action] = now
+			}
+		}
+		g.updatePlayer(&gh.Player, f.DT)
+		if f.Sync != nil {
+			gh.Player.Pos = Vec2{X: f.Sync.X, Y: f.Sync.Y}
+			gh.Player.Vel.Y = f.Sync.VY
+		}
+		gh.Played += f.DT
+		gh.Frame++
+	}
+	if gh.Frame == len(gh.Run.Frames) && gh.EndedAt.IsZero() {
+		gh.EndedAt = g.now
+	}
+}
+
+// drawGhost draws the ghost's ninja, dimmed. A ghost that cleared the stage
+// waits at the goal; one whose run ended early fades out soon after.
+func (g *Game) drawGhost() {
+	gh := g.ghost
+	if gh == nil || (!gh.Run.Clear && !gh.EndedAt.IsZero() && g.now.Sub(gh.EndedAt) > ghostEndLinger) {
+		return
+	}
+	style := tcell.StyleDefault.Foreground(tcell.ColorGray).Dim(true)
+	g.drawNinja(int(gh.Player.Pos.X)-g.cameraX, int(gh.Player.Pos.Y), gh.Player.Facing, style)
+}
+
+// ghostResult compares a finished run with the ghost's, like "best 12.3s"
+func (g *Game) ghostResult() string {
+	switch {
+	case g.newBestRun:
+		return "NEW BEST"
+	case g.ghost == nil:
+		return ""
+	case g.ghost.Run.Clear:
+		return fmt.Sprintf("best %.1fs", g.ghost.Run.Time)
+	default:
+		return fmt.Sprintf("best %d", g.ghost.Run.Score)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestGhostsOnlyOnLayoutsThatComeRoundAgain(t *testing.T) {
+	tests := []struct {
+		name      string
+		ghostDir  bool
+		levelSeed int64
+		want      bool
+	}{
+		{"random layout", true, 0, false},
+		{"layout from --seed", true, 42, true},
+		{"nowhere to keep them", false, 42, false},
+	}
+	for _, tt := range tests {
+		g := newTestGame(t)
+		if tt.ghostDir {
+			g.ghostDir = t.TempDir()
+		}
+		g.levelSeed = tt.levelSeed
+		pressKey(g, tcell.KeyRune, ' ')
+		if got := g.recording != nil; got != tt.want {
+			t.Errorf("%s: recording = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPruneGhosts(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < maxGhosts+5; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("run-%d.json", i))
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		played := start.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(path, played, played)
+	}
+	pruneGhosts(dir)
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != maxGhosts {
+		t.Fatalf("%d runs kept, want %d", len(entries), maxGhosts)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("run-%d.json", i))); err == nil {
+			t.Errorf("run-%d.json, one of the oldest, was kept", i)
+		}
+	}
+}
@@ -2,7 +2,10 @@ module gninja
 
 go 1.21
 
-require github.com/gdamore/tcell/v2 v2.7.0
+require (
+	github.com/gdamore/tcell/v2 v2.7.0
+	golang.org/x/crypto v0.17.0
+)
 
 require (
 	github.com/gdamore/encoding v1.0.0 // indirect
@@ -13,4 +16,3 @@ require (
 	golang.org/x/term v0.15.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
-
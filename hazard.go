@@ -0,0 +1,250 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Fire vents burn for fireBurn out of every fireCycle, flickering for
+// fireWarn beforehand so players can see them coming
+const (
+	fireCycle  = 3 * time.Second
+	fireBurn   = 1 * time.Second
+	fireWarn   = 500 * time.Millisecond
+	fireHeight = 4 // Rows of flame above the vent
+)
+
+// isPit reports whether a ground column is missing
+func (g *Game) isPit(x int) bool {
+	for _, h := range g.hazards {
+		if h.Kind == HazardPit && x >= int(h.X) && x < int(h.X+h.Width) {
+			return true
+		}
+	}
+	return false
+}
+
+// overPit reports whether a body spanning [x, x+width) is entirely over a
+// pit, so there is no ground under it at all
+func (g *Game) overPit(x float64, width int) bool {
+	for _, h := range g.hazards {
+		if h.Kind == HazardPit && x >= h.X && x+float64(width) <= h.X+h.Width {
+			return true
+		}
+	}
+	return false
+}
+
+// confineToPit keeps a body that has dropped below the ground inside the walls
+// of the pit it fell into
+func (g *Game) confineToPit(x *float64, width int) {
+	center := *x + float64(width)/2
+	for _, h := range g.hazards {
+		if h.Kind != HazardPit || center < h.X || center >= h.X+h.Width {
+			continue
+		}
+		*x = math.Max(*x, h.X)
+		*x = math.Min(*x, h.X+h.Width-float64(width))
+		return
+	}
+}
+
+// fireState reports where a fire vent is in its cycle. Each vent is offset by
+// its position so a row of vents doesn't fire in lockstep.
+func (g *Game) fireState(h Hazard) (burning, warning bool) {
+	offset := time.Duration(int(h.X)*137) * time.Millisecond
+	phase := (g.now.Sub(g.runStart) + offset) % fireCycle
+	burning = phase < fireBurn
+	warning = phase >= fireCycle-fireWarn
+	return burning, warning
+}
+
+// hazardHits reports the kind of live hazard a body's bounding box touches,
+// or "" if none. Pits are handled separately since falling into one takes time.
+func (g *Game) hazardHits(pos Vec2, width, height int) string {
+	for _, h := range g.hazards {
+		top, bottom := h.Y, h.Y+1
+		switch h.Kind {
+		case HazardSpikes:
+		case HazardFire:
+			if burning, _ := g.fireState(h); !burning {
+				continue
+			}
+			top = h.Y - fireHeight
+		default:
+			continue
+		}
+		if pos.X < h.X+h.Width &&
+			pos.X+float64(width) > h.X &&
+			pos.Y < bottom &&
+			pos.Y+float64(height) > top {
+			return h.Kind
+		}
+	}
+	return ""
+}
+
+// fellInPit reports whether a body has sunk into a pit far enough that it
+// can't climb out
+func (g *Game) fellInPit(pos Vec2, height int) bool {
+	return pos.Y+float64(height) > float64(g.groundY)+1
+}
+
+// checkPlayerHazards costs players a life if they touch spikes or fire, or
+// fall into a pit
+func (g *Game) checkPlayerHazards() {
+	if g.gameOver || len(g.hazards) == 0 {
+		return
+	}
+	for i := range g.players {
+		p := &g.players[i]
+		if p.Dead {
+			continue
+		}
+		if kind := g.hazardHits(p.Pos, p.Width, p.Height); kind != "" {
+			g.killPlayer(p, kind, nil)
+		} else if g.fellInPit(p.Pos, p.Height) {
+			p.SafeUntil = time.Time{} // Respawn grace doesn't save you from a pit
+			g.killPlayer(p, HazardPit, nil)
+		}
+	}
+}
+
+// checkEnemyHazards kills enemies and corpses caught in spikes, fire or pits.
+// Enemies killed by the level count toward the score of the nearest player.
+func (g *Game) checkEnemyHazards() {
+	if len(g.hazards) == 0 {
+		return
+	}
+
+	for i := range g.enemies {
+		e := &g.enemies[i]
+		if !e.Active {
+			continue
+		}
+		cause := g.hazardHits(e.Pos, e.Width, e.Height)
+		if cause == "" && g.fellInPit(e.Pos, e.Height) {
+			cause = HazardPit
+		}
+		if cause == "" {
+			continue
+		}
+		if g.gameOver {
+			// Nobody is left to score it
+			g.createDeathParticles(e)
+			e.Active = false
+			continue
+		}
+		g.killEnemy(e, g.nearestPlayer(e.Pos), cause)
+	}
+
+	for i := range g.corpses {
+		c := &g.corpses[i]
+		if c.Active && (g.hazardHits(c.Pos, EnemyWidth, EnemyHeight) != "" || g.fellInPit(c.Pos, EnemyHeight)) {
+			g.createDeathParticlesAt(c.Pos, c.Facing, c.EnemyID, c.WasOnPlatform)
+			c.Active = false
+		}
+	}
+}
+
+func (g *Game) drawHazards() {
+	spikeStyle := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	ventStyle := tcell.StyleDefault.Foreground(tcell.ColorDarkRed)
+	warnStyle := tcell.StyleDefault.Foreground(tcell.ColorYellow)
+	flameStyles := []tcell.Style{
+		tcell.StyleDefault.Foreground(tcell.ColorRed),
+		tcell.StyleDefault.Foreground(tcell.ColorOrange),
+		tcell.StyleDefault.Foreground(tcell.ColorYellow),
+	}
+	flameChars := []rune{'^', '*', '\'', '"'}
+	now := g.now
+
+	for _, h := range g.hazards {
+		if h.Kind == HazardPit {
+			continue // Pits are gaps in the ground
+		}
+
+		burning, warning := false, false
+		if h.Kind == HazardFire {
+			burning, warning = g.fireState(h)
+		}
+
+		y := int(h.Y)
+		for x := int(h.X); x < int(h.X+h.Width) && x < g.cameraX+g.width; x++ {
+			if x < g.cameraX {
+				continue
+			}
+			screenX := x - g.cameraX
+
+			switch h.Kind {
+			case HazardSpikes:
+				g.screen.SetContent(screenX, y, '^', nil, spikeStyle)
+			case HazardFire:
+				style := ventStyle
+				// Flicker every 100ms while about to erupt
+				if warning && (now.UnixNano()/int64(100*time.Millisecond))%2 == 0 {
+					style = warnStyle
+				}
+				g.screen.SetContent(screenX, y, '≡', nil, style)
+				if burning {
+					for row := 1; row <= fireHeight; row++ {
+						// Flames thin out toward the top
+						if row > 1 && rand.Float64() < float64(row)/float64(fireHeight+2) {
+							continue
+						}
+						char := flameChars[rand.Intn(len(flameChars))]
+						g.screen.SetContent(screenX, y-row, char, nil, flameStyles[rand.Intn(len(flameStyles))])
+					}
+				}
+			}
+		}
+	}
+}
+
+// addRandomHazards scatters pits, spikes and fire vents along the ground of a
+// generated level, keeping them apart from each other and away from the
+// player's start and the screen edges where enemies walk in
+func addRandomHazards(lvl *Level, screenWidth int, params GenParams, rng *rand.Rand) {
+	groundY := float64(lvl.Height - 1)
+	type span struct{ lo, hi float64 }
+	taken := []span{
+		{0, 8},
+		{float64(screenWidth)/2 - 10, float64(screenWidth)/2 + 10},
+		{float64(lvl.Width) - 8, float64(lvl.Width)},
+	}
+	if lvl.Goal > 0 {
+		taken = append(taken, span{lvl.Goal - 6, lvl.Goal + 6})
+	}
+
+	place := func(kind string, width, y float64) {
+		for attempt := 0; attempt < 20; attempt++ {
+			x := math.Round(rng.Float64() * (float64(lvl.Width) - width))
+			free := true
+			for _, s := range taken {
+				// Leave a run-up of a few columns between hazards
+				if x < s.hi+6 && x+width > s.lo-6 {
+					free = false
+					break
+				}
+			}
+			if free {
+				lvl.Hazards = append(lvl.Hazards, Hazard{Kind: kind, X: x, Y: y, Width: width})
+				taken = append(taken, span{x, x + width})
+				return
+			}
+		}
+	}
+
+	for i := 0; i < params.Pits; i++ {
+		place(HazardPit, math.Round(5+rng.Float64()*2), groundY)
+	}
+	for i := 0; i < params.Spikes; i++ {
+		place(HazardSpikes, math.Round(2+rng.Float64()*3), groundY-1)
+	}
+	for i := 0; i < params.Vents; i++ {
+		place(HazardFire, math.Round(2+rng.Float64()*2), groundY-1)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const maxHighScores = 10 // For each difficulty
+
+// HighScore is one entry on the high score table
+type HighScore struct {
+	Name       string    `json:"name"`
+	Score      int       `json:"score"`
+	Level      string    `json:"level"`
+	Date       time.Time `json:"date"`
+	Difficulty string    `json:"difficulty,omitempty"` // Preset it was played at ("" for Normal, from before there were presets)
+}
+
+// difficulty is the name of the preset a score was played at
+func (e *HighScore) difficulty() string {
+	if e.Difficulty == "" {
+		return normalDifficulty.Name
+	}
+	return e.Difficulty
+}
+
+// HighScores is the high score table. It is shared by every game running in
+// the process (all the sessions of an SSH server), so it locks around access.
+type HighScores struct {
+	mu      sync.Mutex
+	path    string // File the table is saved to ("" to keep it in memory)
+	entries []HighScore
+}
+
+// highScoresPath is where the high score table is kept
+func highScoresPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "gninja", "highscores.json")
+}
+
+// localPlayerName is the name high scores are recorded under when playing
+// in a local terminal
+func localPlayerName() string {
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "player"
+}
+
+// loadHighScores reads the table saved at path. A missing or unreadable file
+// gives an empty table.
+func loadHighScores(path string) *HighScores {
+	h := &HighScores{path: path}
+	if path == "" {
+		return h
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &h.entries)
+	}
+	return h
+}
+
+// Add puts a score on its difficulty's table and saves it. It returns the
+// entry's place on that table (0 for first), or -1 if it didn't make the cut.
+func (h *HighScores) Add(entry HighScore) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = append(h.entries, entry)
+	sort.SliceStable(h.entries, func(i, j int) bool { return h.entries[i].Score > h.entries[j].Score })
+	rank := -1
+	places := make(map[string]int) // Entries kept so far, by difficulty
+	kept := h.entries[:0]
+	for _, e := range h.entries {
+		d := e.difficulty()
+		if places[d] >= maxHighScores {
+			continue
+		}
+		if e == entry && rank < 0 {
+			rank = places[d]
+		}
+		places[d]++
+		kept = append(kept, e)
+	}
+	h.entries = kept
+	if rank < 0 || h.path == "" {
+		return rank, nil
+	}
+	return rank, writeJSONFile(h.path, h.entries)
+}
+
+// Top returns up to n of the best scores at a difficulty
+func (h *HighScores) Top(difficulty string, n int) []HighScore {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var top []HighScore
+	for i := range h.entries {
+		if len(top) < n && h.entries[i].difficulty() == difficulty {
+			top = append(top, h.entries[i])
+		}
+	}
+	return top
+}
+
+// writeJSONFile saves v to path as indented JSON
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic writes data to path by way of a temporary file in the same
+// directory, so a crash can't leave half a file and two games saving at once
+// can't write over each other's temporary file
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// recordScores puts the scores of a finished run on the high score table.
+// Versus matches and editor playtests don't count.
+func (g *Game) recordScores() {
+	g.newHighScore = -1
+	if g.scores == nil || g.match != nil || g.editor != nil || g.inMenu {
+		return
+	}
+	for i := range g.players {
+		p := &g.players[i]
+		if p.Score <= 0 {
+			continue
+		}
+		name := g.playerName
+		if len(g.players) > 1 {
+			name = fmt.Sprintf("%s P%d", name, i+1)
+		}
+		rank, err := g.scores.Add(HighScore{Name: name, Score: p.Score, Level: g.level.Name, Date: time.Now(), Difficulty: g.difficulty.Name})
+		if err != nil {
+			g.toast("High score not saved: " + err.Error())
+		}
+		if rank >= 0 && (g.newHighScore < 0 || rank < g.newHighScore) {
+			g.newHighScore = rank
+		}
+	}
+}
+
+// drawHighScores draws the top of the high score table starting at row y,
+// highlighting the entry the last run earned
+func (g *Game) drawHighScores(y int) {
+	if g.scores == nil {
+		return
+	}
+	top := g.scores.Top(g.difficulty.Name, 5)
+	if len(top) == 0 {
+		return
+	}
+
+	title := "High scores: " + g.difficulty.Name
+	if g.newHighScore >= 0 {
+		title = "NEW HIGH SCORE!"
+	}
+	g.drawText((g.width-len(title))/2, y, title, tcell.StyleDefault.Foreground(tcell.ColorYellow))
+	for i, entry := range top {
+		style := tcell.StyleDefault.Foreground(tcell.ColorWhite)
+		if i == g.newHighScore {
+			style = tcell.StyleDefault.Foreground(tcell.ColorYellow).Bold(true)
+		}
+		line := fmt.Sprintf("%2d. %-16.16s %6d  %s", i+1, entry.Name, entry.Score, entry.Level)
+		g.drawText((g.width-len(line))/2, y+1+i, line, style)
+	}
+}
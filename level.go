@@ -0,0 +1,370 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Enemy kinds that can appear in a level's spawn table
+const (
+	EnemyWalker  = "walker"  // Walks straight at the player
+	EnemyShooter = "shooter" // Keeps its distance and throws shuriken
+)
+
+// Hazard kinds that can appear in a level
+const (
+	HazardPit    = "pit"    // Gap in the ground
+	HazardSpikes = "spikes" // Spike tiles
+	HazardFire   = "fire"   // Fire vent that erupts periodically
+)
+
+// Level describes a hand-authored stage. Coordinates are terminal cells with Y
+// growing downward; the ground sits on the last row (Height-1), and the whole
+// level is shifted on load so it stays anchored to the ground of whatever
+// terminal it's played in.
+type Level struct {
+	Name        string       `json:"name"`
+	Width       int          `json:"width,omitempty"`  // World width; wider than the terminal scrolls (0 = terminal width)
+	Height      int          `json:"height,omitempty"` // Height the level was authored for (0 = terminal height)
+	Seed        int64        `json:"seed,omitempty"`   // Generator seed, for generated levels
+	Goal        float64      `json:"goal,omitempty"`   // X of the finish line for stage levels (0 = endless arena)
+	Platforms   []Platform   `json:"platforms"`
+	Hazards     []Hazard     `json:"hazards,omitempty"`
+	PlayerSpawn *Vec2        `json:"playerSpawn,omitempty"` // Top-left of the player (default: centered on the ground)
+	EnemySpawns []SpawnPoint `json:"enemySpawns,omitempty"` // Where enemies enter (default: both screen edges)
+	SpawnTable  []SpawnEntry `json:"spawnTable,omitempty"`  // Weighted enemy kinds (default: walkers and shooters as the director wants)
+}
+
+// Hazard is a strip of dangerous tiles
+type Hazard struct {
+	Kind  string  `json:"kind"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"` // Row the hazard occupies (pits ignore this and cut the ground)
+	Width float64 `json:"width"`
+}
+
+// SpawnPoint is a place enemies enter the level from
+type SpawnPoint struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`                // Top of the enemy sprite
+	Facing int     `json:"facing,omitempty"` // 0 = face the player
+}
+
+// SpawnEntry is one weighted row of a level's spawn table
+type SpawnEntry struct {
+	Kind   string  `json:"kind"`
+	Weight float64 `json:"weight"`
+}
+
+// levelSource is one place levels can come from: the random generator, a
+// built-in level or a file on disk
+type levelSource struct {
+	name string
+	path string // File the level was loaded from ("" for built-in and random levels)
+	// load makes the level for a screen size; generated levels are laid out
+	// for the player's jump
+	load func(width, height int, seed int64, jump jumpModel) (*Level, error)
+}
+
+//go:embed levels/*.json
+var builtinLevels embed.FS
+
+// LoadLevel reads and validates a level file
+func LoadLevel(path string) (*Level, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseLevel(data, path)
+}
+
+func parseLevel(data []byte, name string) (*Level, error) {
+	var lvl Level
+	if err := json.Unmarshal(data, &lvl); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := lvl.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if lvl.Name == "" {
+		lvl.Name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+	return &lvl, nil
+}
+
+func (l *Level) validate() error {
+	for i, p := range l.Platforms {
+		if p.Width <= 0 {
+			return fmt.Errorf("platform %d: width must be positive", i)
+		}
+	}
+	for i, h := range l.Hazards {
+		switch h.Kind {
+		case HazardPit, HazardSpikes, HazardFire:
+		default:
+			return fmt.Errorf("hazard %d: unknown kind %q", i, h.Kind)
+		}
+		if h.Width <= 0 {
+			return fmt.Errorf("hazard %d: width must be positive", i)
+		}
+	}
+	for i, e := range l.SpawnTable {
+		switch e.Kind {
+		case EnemyWalker, EnemyShooter:
+		default:
+			return fmt.Errorf("spawn table entry %d: unknown enemy kind %q", i, e.Kind)
+		}
+		if e.Weight < 0 {
+			return fmt.Errorf("spawn table entry %d: weight must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// fileLevelSource loads a level from disk every time it is played, so edits
+// show up without restarting
+func fileLevelSource(path string, lvl *Level) levelSource {
+	return levelSource{
+		name: lvl.Name,
+		path: path,
+		load: func(width, height int, seed int64, jump jumpModel) (*Level, error) {
+			return LoadLevel(path)
+		},
+	}
+}
+
+// userLevelDir is where players can drop their own level files
+func userLevelDir() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "gninja", "levels")
+}
+
+// defaultLevelSources lists the random generator presets, then the built-in
+// levels, then any levels found in the user level directory
+func defaultLevelSources() []levelSource {
+	sources := []levelSource{
+		generatorSource("Random", genClassic),
+		generatorSource("Random Towers", genTowers),
+		generatorSource("Random Hard", genHard),
+		generatorSource("Random Stage", genStage),
+	}
+
+	entries, _ := builtinLevels.ReadDir("levels")
+	for _, entry := range entries {
+		data, err := builtinLevels.ReadFile("levels/" + entry.Name())
+		if err != nil {
+			continue
+		}
+		lvl, err := parseLevel(data, entry.Name())
+		if err != nil {
+			continue
+		}
+		sources = append(sources, levelSource{
+			name: lvl.Name,
+			load: func(width, height int, seed int64, jump jumpModel) (*Level, error) { return lvl, nil },
+		})
+	}
+
+	if dir := userLevelDir(); dir != "" {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		sort.Strings(paths)
+		for _, path := range paths {
+			lvl, err := LoadLevel(path)
+			if err != nil {
+				continue
+			}
+			sources = append(sources, fileLevelSource(path, lvl))
+		}
+	}
+	return sources
+}
+
+// addLevelFile loads a level from path, adds it to the level list and selects it
+func (g *Game) addLevelFile(path string) error {
+	lvl, err := LoadLevel(path)
+	if err != nil {
+		return err
+	}
+	g.levels = append(g.levels, fileLevelSource(path, lvl))
+	g.levelIndex = len(g.levels) - 1
+	g.buildLevel()
+	g.resetPlayers()
+	return nil
+}
+
+// buildLevel loads the selected level and places it on the current screen.
+// A level that fails to load falls back to the random generator.
+func (g *Game) buildLevel() {
+	jump := g.playerModel() // Generated platforms are kept in reach of the tuning being played
+	lvl, err := g.levels[g.levelIndex].load(g.width, g.height, g.levelSeed, jump)
+	if err != nil {
+		lvl = generateLevel(g.width, g.height, genClassic, jump, g.levelSeed)
+	}
+	g.level = lvl
+	g.redPlatformTiles = make(map[int]int) // Platform indices change with the layout
+	g.nav = nil
+
+	// The world is never narrower than the screen
+	g.worldWidth = g.width
+	if lvl.Width > g.worldWidth {
+		g.worldWidth = lvl.Width
+	}
+	g.goalX = lvl.Goal
+
+	// Shift everything down so the level's ground row lines up with ours
+	shift := 0.0
+	if lvl.Height > 0 {
+		shift = float64(g.groundY - (lvl.Height - 1))
+	}
+
+	g.platforms = make([]Platform, 0, len(lvl.Platforms))
+	for _, p := range lvl.Platforms {
+		if p.Height == 0 {
+			p.Height = 1.0
+		}
+		p.Y += shift
+		g.platforms = append(g.platforms, p)
+	}
+
+	g.hazards = make([]Hazard, 0, len(lvl.Hazards))
+	for _, h := range lvl.Hazards {
+		h.Y += shift
+		g.hazards = append(g.hazards, h)
+	}
+
+	g.enemySpawns = make([]SpawnPoint, 0, len(lvl.EnemySpawns))
+	for _, s := range lvl.EnemySpawns {
+		s.Y += shift
+		g.enemySpawns = append(g.enemySpawns, s)
+	}
+
+	g.playerSpawn = nil
+	if lvl.PlayerSpawn != nil {
+		g.playerSpawn = &Vec2{X: lvl.PlayerSpawn.X, Y: lvl.PlayerSpawn.Y + shift}
+	}
+}
+
+// pickEnemyKind chooses the kind of the next enemy from the level's spawn
+// table, or makes it a shooter as often as the director wants if the level
+// doesn't have one
+func (g *Game) pickEnemyKind() string {
+	table := g.level.SpawnTable
+	weight := func(entry SpawnEntry) float64 {
+		if entry.Kind == EnemyShooter {
+			return g.director.shooterWeight(entry.Weight)
+		}
+		return entry.Weight
+	}
+	total := 0.0
+	for _, entry := range table {
+		total += weight(entry)
+	}
+	if total <= 0 {
+		// Spread the share of shooters the director wants evenly
+		n := float64(g.enemySpawnCounter)
+		g.enemySpawnCounter++
+		if math.Floor((n+1)*g.director.Shooters) > math.Floor(n*g.director.Shooters) {
+			return EnemyShooter
+		}
+		return EnemyWalker
+	}
+
+	r := g.rng.Float64() * total
+	for _, entry := range table {
+		r -= weight(entry)
+		if r < 0 {
+			return entry.Kind
+		}
+	}
+	return table[len(table)-1].Kind
+}
+
+// pickEnemySpawn chooses where the next enemy enters: one of the level's spawn
+// points near the screen, or just off the left or right edge of the screen
+// (usually the side fewer enemies are coming from)
+func (g *Game) pickEnemySpawn() SpawnPoint {
+	nearby := make([]SpawnPoint, 0, len(g.enemySpawns))
+	for _, s := range g.enemySpawns {
+		if g.inView(s.X, float64(g.width/2)) {
+			nearby = append(nearby, s)
+		}
+	}
+	if len(nearby) > 0 {
+		return nearby[g.rng.Intn(len(nearby))]
+	}
+	left := g.rng.Float64() < 0.5
+	if side := g.thinSide(); side != 0 && g.rng.Float64() < spawnFlankChance {
+		left = side < 0 // Come in from the other side, to pincer the player
+	}
+	if left {
+		return SpawnPoint{X: float64(g.cameraX - EnemyWidth), Y: float64(g.groundY - EnemyHeight), Facing: 1}
+	}
+	return SpawnPoint{X: float64(g.cameraX + g.width), Y: float64(g.groundY - EnemyHeight), Facing: -1}
+}
+
+func (g *Game) drawLevelSelect() {
+	title := "Select Level"
+	titleY := g.height/2 - len(g.levels)/2 - 2
+	if titleY < 1 {
+		titleY = 1
+	}
+	greenStyle := tcell.StyleDefault.Foreground(tcell.ColorGreen)
+	for i, r := range title {
+		g.screen.SetContent((g.width-len(title))/2+i, titleY, r, nil, greenStyle)
+	}
+
+	for i, source := range g.levels {
+		line := "  " + source.name + "  "
+		style := tcell.StyleDefault
+		if i == g.levelCursor {
+			line = "> " + source.name + " <"
+			style = greenStyle
+		}
+		lineX := (g.width - len(line)) / 2
+		for j, r := range line {
+			g.screen.SetContent(lineX+j, titleY+2+i, r, nil, style)
+		}
+	}
+
+	help := "Up/Down to choose, ENTER to select, ESC to go back"
+	for i, r := range help {
+		g.screen.SetContent((g.width-len(help))/2+i, titleY+3+len(g.levels), r, nil, tcell.StyleDefault)
+	}
+}
+
+func (g *Game) handleLevelSelectInput(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		g.levelCursor = (g.levelCursor + len(g.levels) - 1) % len(g.levels)
+	case tcell.KeyDown:
+		g.levelCursor = (g.levelCursor + 1) % len(g.levels)
+	case tcell.KeyEnter:
+		g.levelIndex = g.levelCursor
+		if g.editor != nil {
+			// Opened from the editor: edit the chosen level
+			g.menuScreen = screenMain
+			g.openEditor()
+			return
+		}
+		g.buildLevel()
+		g.resetPlayers()
+		g.demo = nil // The demo starts again on the new level
+		g.menuScreen = screenMain
+	case tcell.KeyEscape:
+		g.menuScreen = screenMain
+		if g.editor != nil {
+			g.menuScreen = screenEditor
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+// jumpModel describes how a body moves through the air, integrated the same
+// way updatePlayer and updateEnemies do it: once per frame, velocity first,
+// then position. Keep the numbers in step with those functions.
+type jumpModel struct {
+	Gravity   float64 // pixels per second squared
+	JumpSpeed float64 // Initial vertical velocity of a jump (negative is up)
+	AirSpeed  float64 // Horizontal speed while airborne
+	Width     int
+	Height    int
+}
+
+// playerJump is how players move with the default tuning. Game.playerModel
+// adjusts it for the tuning being played.
+var playerJump = jumpModel{Gravity: defaultTuning.Gravity, JumpSpeed: defaultTuning.JumpSpeed, AirSpeed: defaultTuning.AirSpeed, Width: PlayerWidth, Height: PlayerHeight}
+
+// enemyJump is how enemies move with the default tuning, on the ground as
+// well as in the air. Game.enemyModel adjusts it for the tuning and
+// difficulty being played.
+var enemyJump = jumpModel{Gravity: defaultTuning.Gravity, JumpSpeed: defaultTuning.JumpSpeed, AirSpeed: 20.0, Width: EnemyWidth, Height: EnemyHeight}
+
+// arcPoint is where a jumping body is, relative to its take-off position,
+// at the end of a frame
+type arcPoint struct {
+	Time float64 // Seconds since take-off
+	DY   float64 // Vertical offset of the top of the body (negative is up)
+	VelY float64
+}
+
+// arc simulates a jump frame by frame until the body is falling and has
+// dropped more than maxDrop rows below where it started
+func (m jumpModel) arc(maxDrop float64) []arcPoint {
+	dt := FrameDuration.Seconds()
+	points := make([]arcPoint, 0, 64)
+	vel := m.JumpSpeed
+	y := 0.0
+	for frame := 1; vel <= 0 || y <= maxDrop; frame++ {
+		vel += m.Gravity * dt
+		y += vel * dt
+		points = append(points, arcPoint{Time: float64(frame) * dt, DY: y, VelY: vel})
+	}
+	return points
+}
+
+// maxJumpHeight is how many rows the top of the body rises during a jump
+func (m jumpModel) maxJumpHeight() float64 {
+	peak := 0.0
+	for _, p := range m.arc(0) {
+		peak = math.Min(peak, p.DY)
+	}
+	return -peak
+}
+
+// horizontalGap is how far a body standing anywhere on from has to travel
+// sideways to be over any part of to
+func (m jumpModel) horizontalGap(from, to Platform) float64 {
+	w := float64(m.Width)
+	// A body overlaps a platform when its X is in (p.X-w, p.X+p.Width)
+	gap := math.Max(to.X-w-(from.X+from.Width), from.X-w-(to.X+to.Width))
+	return math.Max(gap, 0)
+}
+
+// canJump reports whether a body standing on from can jump and land on top of
+// to, with slack cells of horizontal reach to spare. Landing follows the
+// platform collision rule: falling, with the top of the body above the
+// platform and the bottom below it. Frames that only just land are rejected,
+// since rounding in the real update can tip them either way.
+func (m jumpModel) canJump(from, to Platform, slack float64) bool {
+	const margin = 0.01
+	startY := from.Y - float64(m.Height)
+	gap := m.horizontalGap(from, to) + slack + margin
+	for _, p := range m.arc(to.Y - startY) {
+		if p.VelY <= 0 {
+			continue
+		}
+		top := startY + p.DY
+		if top < to.Y-margin && top+float64(m.Height) > to.Y+margin && m.AirSpeed*p.Time >= gap {
+			return true
+		}
+	}
+	return false
+}
+
+// GenParams tunes the procedural level generator
+type GenParams struct {
+	Tiers    int     // Layers of platforms stacked above the ground
+	MinRise  float64 // Rows between a tier and the one below it
+	MaxRise  float64
+	MinWidth float64 // Platform widths
+	MaxWidth float64
+	MinGap   float64 // Horizontal space between platforms on the same tier
+	MaxGap   float64
+	Jitter   float64 // Rows a platform can sit above or below its tier
+	Slack    float64 // Spare horizontal reach every jump must have, in cells
+	Screens  int     // World width in screens (0 or 1 = a single-screen arena)
+	Goal     bool    // Put a finish line at the far end (stage mode)
+	Pits     int     // Gaps in the ground
+	Spikes   int     // Strips of spikes on the ground
+	Vents    int     // Fire vents on the ground
+}
+
+// Generator presets offered in the level menu
+var (
+	genClassic = GenParams{Tiers: 1, MinRise: 5, MaxRise: 13, MinWidth: 12, MaxWidth: 28, MinGap: 4, MaxGap: 14, Jitter: 0, Slack: 2}
+	genTowers  = GenParams{Tiers: 3, MinRise: 5, MaxRise: 8, MinWidth: 10, MaxWidth: 20, MinGap: 3, MaxGap: 12, Jitter: 1, Slack: 2}
+	genHard    = GenParams{Tiers: 3, MinRise: 7, MaxRise: 10, MinWidth: 5, MaxWidth: 10, MinGap: 8, MaxGap: 18, Jitter: 2, Slack: 0, Pits: 1, Spikes: 1, Vents: 1}
+	genStage   = GenParams{Tiers: 2, MinRise: 5, MaxRise: 8, MinWidth: 8, MaxWidth: 20, MinGap: 4, MaxGap: 16, Jitter: 2, Slack: 1, Screens: 4, Goal: true, Pits: 3, Spikes: 3, Vents: 2}
+)
+
+// generatorSource offers a generator preset as a level source
+func generatorSource(name string, params GenParams) levelSource {
+	return levelSource{
+		name: name,
+		load: func(width, height int, seed int64, jump jumpModel) (*Level, error) {
+			lvl := generateLevel(width, height, params, jump, seed)
+			lvl.Name = name
+			return lvl, nil
+		},
+	}
+}
+
+// generateLevel builds a random layout in which every platform can be reached
+// from the ground by the player's jump. Platforms are laid out tier by tier,
+// left to right; each one must be reachable from something already placed, and
+// is lowered a row at a time until it is (or dropped if it never is). The same
+// seed and jump always give the same layout; seed 0 picks one at random. width
+// is the screen width; params.Screens makes the world wider.
+func generateLevel(width, height int, params GenParams, jump jumpModel, seed int64) *Level {
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	screenWidth := width
+	if params.Screens > 1 {
+		width *= params.Screens
+	}
+
+	groundY := float64(height - 1)
+	topLimit := float64(PlayerHeight + 2) // Leave room for the score line and a player standing on top
+	ground := Platform{X: 0, Y: groundY, Width: float64(width), Height: 1}
+
+	reachable := []Platform{ground}
+	platforms := make([]Platform, 0)
+
+	between := func(lo, hi float64) float64 { return lo + rng.Float64()*(hi-lo) }
+
+	tierY := groundY
+	for tier := 0; tier < params.Tiers; tier++ {
+		tierY -= math.Round(between(params.MinRise, params.MaxRise))
+		if tierY < topLimit {
+			break
+		}
+
+		x := between(1, params.MaxGap)
+		for {
+			w := math.Round(between(params.MinWidth, params.MaxWidth))
+			if x+w > float64(width)-1 {
+				break
+			}
+			y := tierY + math.Round(between(-params.Jitter, params.Jitter))
+			if y < topLimit {
+				y = topLimit
+			}
+
+			p := Platform{X: math.Round(x), Y: y, Width: w, Height: 1}
+			for ; p.Y < groundY-float64(PlayerHeight); p.Y++ {
+				if !blocksPlatforms(p, platforms) && jump.reachableFrom(p, reachable, params.Slack) {
+					platforms = append(platforms, p)
+					reachable = append(reachable, p)
+					break
+				}
+			}
+
+			x += w + between(params.MinGap, params.MaxGap)
+		}
+	}
+
+	lvl := &Level{Name: "Random", Width: width, Height: height, Seed: seed, Platforms: platforms}
+	if params.Goal {
+		lvl.Goal = float64(width - 6)
+	}
+	addRandomHazards(lvl, screenWidth, params, rng)
+	return lvl
+}
+
+// blocksPlatforms reports whether p would overlap another platform or leave too
+// little headroom for a player standing on or under it
+func blocksPlatforms(p Platform, others []Platform) bool {
+	for _, o := range others {
+		if p.X >= o.X+o.Width+1 || o.X >= p.X+p.Width+1 {
+			continue // Side by side with at least a column between
+		}
+		if math.Abs(p.Y-o.Y) <= float64(PlayerHeight) {
+			return true
+		}
+	}
+	return false
+}
+
+// reachableFrom reports whether a body can jump onto p from any of surfaces
+func (m jumpModel) reachableFrom(p Platform, surfaces []Platform, slack float64) bool {
+	for _, s := range surfaces {
+		if m.canJump(s, p, slack) {
+			return true
+		}
+	}
+	return false
+}
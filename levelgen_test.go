@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCanJump(t *testing.T) {
+	ground := Platform{X: 0, Y: 29, Width: 100, Height: 1}
+	ledge := Platform{X: 0, Y: 20, Width: 10, Height: 1}
+	tests := []struct {
+		name     string
+		from, to Platform
+		slack    float64
+		want     bool
+	}{
+		{"low platform overhead", ground, Platform{X: 40, Y: 24, Width: 10}, 0, true},
+		{"highest reachable rise", ground, Platform{X: 40, Y: 16, Width: 10}, 0, true},
+		{"one row too high", ground, Platform{X: 40, Y: 15, Width: 10}, 0, false},
+		{"level gap in reach", ledge, Platform{X: 38, Y: 20, Width: 10}, 0, true},
+		{"level gap with no reach to spare", ledge, Platform{X: 38, Y: 20, Width: 10}, 2, false},
+		{"level gap out of reach", ledge, Platform{X: 40, Y: 20, Width: 10}, 0, false},
+		{"falling further reaches further", ledge, Platform{X: 40, Y: 28, Width: 10}, 0, true},
+		{"back down to the ground", ledge, ground, 0, true},
+	}
+	for _, tt := range tests {
+		if got := playerJump.canJump(tt.from, tt.to, tt.slack); got != tt.want {
+			t.Errorf("%s: canJump = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// checkReachable fails the test unless every platform of a generated layout
+// can be reached from the ground by a chain of jumps, each with the preset's
+// slack to spare
+func checkReachable(t *testing.T, name string, lvl *Level, params GenParams, jump jumpModel) {
+	t.Helper()
+	ground := Platform{X: 0, Y: float64(lvl.Height - 1), Width: float64(lvl.Width), Height: 1}
+	reached := []Platform{ground}
+	left := append([]Platform(nil), lvl.Platforms...)
+	for progress := true; progress; {
+		progress = false
+		for i := 0; i < len(left); i++ {
+			if jump.reachableFrom(left[i], reached, params.Slack) {
+				reached = append(reached, left[i])
+				left = append(left[:i], left[i+1:]...)
+				i--
+				progress = true
+			}
+		}
+	}
+	if len(left) > 0 {
+		t.Errorf("%s: platforms %v can't be reached", name, left)
+	}
+}
+
+func TestGenerateLevelReachable(t *testing.T) {
+	presets := map[string]GenParams{"classic": genClassic, "towers": genTowers, "hard": genHard, "stage": genStage}
+	for name, params := range presets {
+		for seed := int64(1); seed <= 50; seed++ {
+			lvl := generateLevel(100, 30, params, playerJump, seed)
+			checkReachable(t, fmt.Sprintf("%s seed %d", name, seed), lvl, params, playerJump)
+
+			if again := generateLevel(100, 30, params, playerJump, seed); len(again.Platforms) != len(lvl.Platforms) {
+				t.Errorf("%s seed %d: layout changed between runs", name, seed)
+			} else {
+				for i := range again.Platforms {
+					if again.Platforms[i] != lvl.Platforms[i] {
+						t.Errorf("%s seed %d: platform %d changed between runs", name, seed, i)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateLevelReachableWithLowJump(t *testing.T) {
+	g := newTestGame(t)
+	g.tuning.JumpSpeed = defaultTuning.JumpSpeed * 0.8
+	g.tuning.Gravity = defaultTuning.Gravity * 1.2
+	jump := g.playerModel()
+	if jump.maxJumpHeight() >= playerJump.maxJumpHeight()-2 {
+		t.Fatalf("tuned jump rises %.1f rows, not much below the default %.1f", jump.maxJumpHeight(), playerJump.maxJumpHeight())
+	}
+
+	for seed := int64(1); seed <= 50; seed++ {
+		for name, params := range map[string]GenParams{"classic": genClassic, "towers": genTowers} {
+			lvl := generateLevel(100, 30, params, jump, seed)
+			checkReachable(t, fmt.Sprintf("%s seed %d", name, seed), lvl, params, jump)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"math"
 	"math/rand"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -18,13 +21,28 @@ const (
 
 	EnemyWidth  = 4
 	EnemyHeight = 3
+
+	playerProjectileSpeed = 200.0 // pixels per second
+	enemyProjectileSpeed  = 80.0  // pixels per second - even slower for easier dodging
+	projectileSweepSteps  = 5     // Steps a projectile's path over a frame is checked for hits in
+
+	enemyJumpCooldown = time.Second // Shortest time between an enemy's jumps
 )
 
+// simEpoch is where game clocks start. Timers run off the game clock rather
+// than the wall clock, so they stand still while the game isn't updating and
+// can be saved as they are. Zero timers mean "never", so any time well after
+// the zero time works.
+var simEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 type Vec2 struct {
-	X, Y float64
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
 }
 
 type Player struct {
+	Index            int         // 0 for player one, 1 for player two
+	Color            tcell.Color // Sprite color
 	Pos              Vec2
 	Vel              Vec2 // Velocity for jumping
 	Facing           int  // -1 for left, 1 for right (sprite direction)
@@ -34,29 +52,50 @@ type Player struct {
 	OnGround         bool
 	OnPlatform       bool      // true if player is on a platform
 	LastOnGroundTime time.Time // Track when player left ground/platform for coyote time
+	Score            int
+	Lives            int                   // Lives left, counting the current one
+	Dead             bool                  // true while down (waiting to respawn or out of lives)
+	RespawnAt        time.Time             // When a downed player with lives left comes back
+	SafeUntil        time.Time             // Player can't be hurt until this time (just respawned)
+	Pressed          [numActions]time.Time // Last time each action's key was pressed
+	LastShot         time.Time
+	Jumps            int // Jumps made this run
 }
 
 type Projectile struct {
 	Pos     Vec2
-	PrevPos Vec2 // Previous position for swept collision detection
-	Dir     int  // -1 for left, 1 for right
+	PrevPos Vec2    // Previous position for swept collision detection
+	Dir     int     // -1 for left, 1 for right
+	Vel     Vec2    // Velocity of an aimed enemy shot (zero flies flat along Dir at the owner's speed)
+	Gravity float64 // Pulls a lobbed shot down
 	Active  bool
 	Frame   int
-	IsEnemy bool // true if fired by enemy, false if fired by player
+	Owner   int // Index of the player who threw it, or ownerEnemy
 }
 
+// ownerEnemy is the Owner of projectiles fired by enemies
+const ownerEnemy = -1
+
 type Enemy struct {
+	ID            int // Unique within a run, so network clients can follow an enemy between snapshots
 	Pos           Vec2
 	Vel           Vec2 // Velocity for jumping
 	Facing        int  // -1 for left, 1 for right
 	Width         int
 	Height        int
 	Active        bool
+	Kind          string        // Spawn table kind (EnemyWalker or EnemyShooter)
 	CanShoot      bool          // true if this enemy can fire projectiles
+	Pattern       string        // How a shooting enemy fires (PatternBurst, PatternSpread or PatternLob)
+	Burst         int           // Shots left in the burst being fired
+	Role          int           // What the director has it doing (roleChase, roleHold or roleShoot)
+	Post          float64       // How far from the player it keeps when holding back or shooting
+	HoldUntil     time.Time     // When it stops holding back for a pincer (zero if it hasn't yet)
 	LastShot      time.Time     // Last time this enemy fired
 	NextShotDelay time.Duration // Random delay between 1-3 seconds for next shot
 	OnGround      bool
 	JumpCooldown  time.Time // Cooldown to prevent constant jumping
+	NavTo         int       // Surface in the nav graph it's jumping or dropping to (-1 if none)
 }
 
 type DeathParticle struct {
@@ -68,10 +107,10 @@ type DeathParticle struct {
 	GroundTime              time.Time // When it first hit the ground
 	Active                  bool
 	EnemyID                 int       // ID of the enemy this particle came from
-	IsRed                   bool      // 20% chance to be red
+	IsRed                   bool      // Bloodied (Tuning.RedChance)
 	AngularVel              float64   // Angular velocity for rotation effect
 	Angle                   float64   // Current rotation angle
-	FallsThrough            bool      // 30% chance to fall through ground
+	FallsThrough            bool      // Falls through the ground (Tuning.FallThroughChance)
 	IsHead                  bool      // true if this is the 'O' head piece
 	IsRolling               bool      // true if head piece is rolling
 	RollDistance            float64   // Distance to roll
@@ -109,111 +148,221 @@ type Corpse struct {
 }
 
 type Platform struct {
-	X      float64 // Left edge X position
-	Y      float64 // Top edge Y position
-	Width  float64 // Platform width
-	Height float64 // Platform height (usually 1)
+	X      float64 `json:"x"`                // Left edge X position
+	Y      float64 `json:"y"`                // Top edge Y position
+	Width  float64 `json:"width"`            // Platform width
+	Height float64 `json:"height,omitempty"` // Platform height (usually 1)
 }
 
+// Menu pages
+const (
+	screenMain         = iota // Title screen
+	screenLevels              // Level select
+	screenEditor              // Level editor
+	screenStats               // Lifetime stats
+	screenAchievements        // Achievement gallery
+)
+
 type Game struct {
-	screen             tcell.Screen
-	player             Player
-	projectiles        []Projectile
-	enemies            []Enemy
-	deathParticles     []DeathParticle
-	corpses            []Corpse
-	bloodParticles     []BloodParticle
-	platforms          []Platform
-	score              int
-	enemiesDefeated    int // Track number of enemies defeated
-	enemySpawnCounter  int // Counter to track every other enemy for shooting
-	gameOver           bool
-	inMenu             bool // true when showing main menu
-	bloodColorMode     int  // 0=red, 1=green, 2=rainbow, 3=off
-	width              int
-	height             int
-	groundY            int
-	redGroundTiles     map[int]int // Tracks which ground tiles are red (key is x position, value is enemy ID)
-	redPlatformTiles   map[int]int // Tracks which platform tiles are red (key is platform index + x offset, value is enemy ID)
-	nextEnemyID        int         // Counter for assigning unique enemy IDs
-	lastFrame          time.Time
-	keys               map[tcell.Key]time.Time
-	lastShot           time.Time
-	menuLastShot       time.Time // Last time menu player fired
-	menuLastEnemySpawn time.Time // Last time enemy spawned in menu
+	screen            tcell.Screen
+	players           []Player // Player one first; the menu demo only uses player one
+	numPlayers        int      // Players in the current run (2 = co-op or versus)
+	match             *Match   // Versus match being played (nil outside versus)
+	versusRounds      int      // Rounds to win a versus match
+	versusEnemies     bool     // Whether neutral enemies spawn in versus
+	projectiles       []Projectile
+	enemies           []Enemy
+	deathParticles    []DeathParticle
+	corpses           []Corpse
+	bloodParticles    []BloodParticle
+	platforms         []Platform
+	enemiesDefeated   int // Track number of enemies defeated
+	enemySpawnCounter int // Enemies spawned without a spawn table, to spread the shooters evenly among them
+	gameOver          bool
+	inMenu            bool    // true when showing main menu
+	menuScreen        int     // Which menu page is showing (screenMain, screenLevels, screenEditor, ...)
+	editor            *Editor // Level editor state (nil when not editing)
+	bloodColorMode    int     // 0=red, 1=green, 2=rainbow, 3=off
+	width             int     // Screen width
+	height            int
+	groundY           int
+	worldWidth        int       // Level width (at least the screen width)
+	cameraX           int       // World X of the left edge of the screen
+	goalX             float64   // World X of the stage goal (0 = endless arena)
+	stageClear        bool      // true once the player reaches a stage's goal
+	now               time.Time // Game clock: starts at simEpoch and advances with each update
+	runStart          time.Time // When the current run started
+	runTime           time.Duration
+	redGroundTiles    map[int]int // Tracks which ground tiles are red (key is x position, value is enemy ID)
+	redPlatformTiles  map[int]int // Tracks which platform tiles are red (key is platform index + x offset, value is enemy ID)
+	hazards           []Hazard
+	enemySpawns       []SpawnPoint     // Level spawn points (empty = screen edges)
+	playerSpawn       *Vec2            // Level player spawn (nil = centered on the ground)
+	level             *Level           // Level currently being played
+	levels            []levelSource    // Levels available from the level select menu
+	levelIndex        int              // Selected entry in levels
+	levelCursor       int              // Highlighted entry while the level select menu is open
+	levelSeed         int64            // Seed for generated levels (0 = new layout every time)
+	nextEnemyID       int              // Counter for assigning unique enemy IDs
+	enemySerial       int              // Counter for Enemy.ID
+	host              *netHost         // Accepts network players (nil unless hosting)
+	client            *netClient       // Connection to the host when playing on a remote game
+	live              *liveGame        // Entry spectators watch this game through (nil if it can't be watched)
+	watching          string           // What a spectator is watching (empty when playing)
+	rng               *rand.Rand       // Randomness of a run's enemy spawns, seeded so a ghost's run can be repeated
+	rngSource         *rngSource       // Source behind rng, which can be saved
+	savePath          string           // Where quitting saves a run in progress ("" to not save)
+	hasSave           bool             // A saved run is waiting to be continued
+	menuNote          string           // Problem to report on the title screen
+	ghostsOn          bool             // Record solo runs and race the best one
+	ghostDir          string           // Where the best runs are kept ("" to not record or race them)
+	recording         *Recording       // The run being played, for its ghost
+	ghost             *Ghost           // Best run on this layout, raced as a ghost (nil if none)
+	newBestRun        bool             // The run that just ended was saved as the new ghost
+	scores            *HighScores      // High score table (shared between SSH sessions)
+	playerName        string           // Name high scores are recorded under
+	newHighScore      int              // Place the last run took on the high score table (-1 if none)
+	stats             *StatsBook       // Lifetime stats (shared between SSH sessions)
+	runStats          Stats            // Stats of the run being played
+	lastDeath         string           // How the last player to die died
+	achievements      *AchievementBook // Unlocked achievements (shared between SSH sessions)
+	settings          *SettingsBook    // Title screen choices, like the difficulty (shared between SSH sessions)
+	tuning            Tuning           // Physics and feel, from the tuning file
+	tuningPath        string           // Tuning file watched for changes ("" for none)
+	tuningChecked     time.Time        // When the tuning file was last checked (wall clock)
+	tuningModified    time.Time        // Modification time of the tuning file as last loaded
+	lifetime          Stats            // Player's lifetime stats as of the start of the run
+	achieved          map[string]bool  // Achievements the player has, by ID
+	recentKills       []time.Time      // When player one's last few kills were
+	toasts            []toast          // Notifications showing during play
+	events            eventBus         // Things happening in the run, for scoring, stats and achievements
+	spawnRate         float64          // Chance of an enemy spawning each frame (0 until the first frame of a run)
+	telemetry         *telemetry       // Log of the session's events (nil unless --telemetry is given)
+	nav               *navGraph        // Where enemies can get to on the layout (nil until needed or after it changes)
+	difficulty        Difficulty       // Preset new runs play at
+	pickedDifficulty  Difficulty       // Preset to go back to once a continued run played at its own ends (no Name if none)
+	director          director         // Pacing of the run's spawns
+	debugOn           bool             // Showing the debug overlay (F3)
+	demo              *aiPlayer        // Plays the attract-mode demo behind the menu (nil until it starts)
+	demoEnded         time.Time        // When the last demo run ended
+	lastFrame         time.Time
+	frameTime         time.Duration // Wall-clock time between the last two frames, for the debug overlay
 }
 
 func NewGame(screen tcell.Screen) *Game {
 	width, height := screen.Size()
 	groundY := height - 1 // Ground at the bottom of the terminal
 
-	// Create floating platforms
-	platforms := make([]Platform, 0)
-	// Add 3-5 platforms at various heights
-	numPlatforms := 3 + rand.Intn(3) // 3-5 platforms
-	platformSpacing := float64(width) / float64(numPlatforms+1)
-	groundLevel := float64(groundY)
-	for i := 0; i < numPlatforms; i++ {
-		platformX := platformSpacing * float64(i+1)
-		// Platforms at different heights: 4-12 pixels above ground (reachable with jump)
-		// Player can jump about 12 pixels high, so platforms should be within that range
-		heightAboveGround := 4.0 + rand.Float64()*8.0      // 4-12 pixels above ground
-		platformY := groundLevel - heightAboveGround - 1.0 // -1 to account for platform height
-		platformWidth := 12.0 + rand.Float64()*16.0        // 12-28 wide - wider platforms
-		platforms = append(platforms, Platform{
-			X:      platformX - platformWidth/2,
-			Y:      platformY,
-			Width:  platformWidth,
-			Height: 1.0,
-		})
-	}
+	g := &Game{
+		screen:            screen,
+		projectiles:       make([]Projectile, 0),
+		enemies:           make([]Enemy, 0),
+		deathParticles:    make([]DeathParticle, 0),
+		corpses:           make([]Corpse, 0),
+		bloodParticles:    make([]BloodParticle, 0),
+		numPlayers:        1,
+		versusRounds:      3,
+		gameOver:          false,
+		inMenu:            true,
+		menuScreen:        screenMain,
+		bloodColorMode:    0, // Start with red
+		width:             width,
+		height:            height,
+		groundY:           groundY,
+		redGroundTiles:    make(map[int]int),
+		redPlatformTiles:  make(map[int]int),
+		levels:            defaultLevelSources(),
+		levelIndex:        0, // Random
+		newHighScore:      -1,
+		ghostsOn:          true,
+		difficulty:        normalDifficulty,
+		tuning:            defaultTuning,
+		nextEnemyID:       1,
+		enemiesDefeated:   0,
+		enemySpawnCounter: 0,
+		now:               simEpoch,
+		lastFrame:         time.Now(),
+	}
+	g.seedRNG(time.Now().UnixNano(), 0)
+	g.events.subscribe(g.scoreEvent)
+	g.events.subscribe(g.statsEvent)
+	g.events.subscribe(g.achievementEvent)
+	g.events.subscribe(g.directorEvent)
+	g.buildLevel()
+	g.resetPlayers()
+	return g
+}
 
-	return &Game{
-		screen: screen,
-		player: Player{
-			Pos:              Vec2{X: float64(width / 2), Y: float64(groundY - PlayerHeight)},
-			Vel:              Vec2{X: 0, Y: 0},
-			Facing:           1,
-			MoveDir:          0,
-			Width:            PlayerWidth,
-			Height:           PlayerHeight,
-			OnGround:         true,
-			OnPlatform:       false,
-			LastOnGroundTime: time.Now(),
-		},
-		projectiles:        make([]Projectile, 0),
-		enemies:            make([]Enemy, 0),
-		deathParticles:     make([]DeathParticle, 0),
-		corpses:            make([]Corpse, 0),
-		bloodParticles:     make([]BloodParticle, 0),
-		platforms:          platforms,
-		score:              0,
-		gameOver:           false,
-		inMenu:             true,
-		bloodColorMode:     0, // Start with red
-		width:              width,
-		height:             height,
-		groundY:            groundY,
-		redGroundTiles:     make(map[int]int),
-		redPlatformTiles:   make(map[int]int),
-		nextEnemyID:        1,
-		enemiesDefeated:    0,
-		enemySpawnCounter:  0,
-		lastFrame:          time.Now(),
-		keys:               make(map[tcell.Key]time.Time),
-		lastShot:           time.Time{},
-		menuLastShot:       time.Time{},
-		menuLastEnemySpawn: time.Time{},
+// resetRun clears everything left over from the menu demo or a previous run
+// and puts the player at the spawn point of the current layout
+func (g *Game) resetRun() {
+	g.projectiles = make([]Projectile, 0)
+	g.enemies = make([]Enemy, 0)
+	g.corpses = make([]Corpse, 0)
+	g.deathParticles = make([]DeathParticle, 0)
+	g.bloodParticles = make([]BloodParticle, 0)
+	g.redGroundTiles = make(map[int]int)
+	g.redPlatformTiles = make(map[int]int)
+	g.nav = nil // The layout may have been edited
+
+	g.resetPlayers()
+
+	g.enemiesDefeated = 0
+	g.enemySpawnCounter = 0
+	g.gameOver = false
+	g.stageClear = false
+	g.runStart = g.now
+	g.runTime = 0
+	g.runStats = Stats{}
+	g.lastDeath = ""
+	g.toasts = nil
+	g.loadProfile()
+	g.nextEnemyID = 1
+	g.spawnRate = 0
+	g.resetDirector()
+	g.startGhostRun()
+	g.events.publish(RunStarted{Level: g.level.Name, Seed: g.rngSource.seed, Players: len(g.players), Versus: g.match != nil, Difficulty: g.difficulty.Name})
+}
+
+// resetPlayers puts fresh players at the level's spawn point, standing still
+func (g *Game) resetPlayers() {
+	lives := g.difficulty.Lives
+	if g.match != nil {
+		lives = 1
+	} else if g.numPlayers > 1 {
+		lives = g.difficulty.CoopLives
+	}
+	g.players = make([]Player, g.numPlayers)
+	for i := range g.players {
+		if g.match != nil {
+			pos, facing := g.versusSpawn(i)
+			g.players[i] = g.newPlayer(i, pos, lives)
+			g.players[i].Facing = facing
+			continue
+		}
+		g.players[i] = g.newPlayer(i, g.spawnPos(i), lives)
 	}
+
+	// Start the camera at the left edge and scroll to the player
+	g.cameraX = 0
+	g.updateCamera()
 }
 
-func (g *Game) drawPlayer() {
-	x := int(g.player.Pos.X)
-	y := int(g.player.Pos.Y)
+func (g *Game) drawPlayer(p *Player) {
+	if p.Dead {
+		return
+	}
+	// Blink while just respawned
+	if g.now.Before(p.SafeUntil) && g.now.UnixNano()/int64(100*time.Millisecond)%2 == 0 {
+		return
+	}
 
-	style := tcell.StyleDefault.Foreground(tcell.ColorBlue)
+	g.drawNinja(int(p.Pos.X)-g.cameraX, int(p.Pos.Y), p.Facing, tcell.StyleDefault.Foreground(p.Color))
+}
 
-	if g.player.Facing == 1 { // Facing right
+// drawNinja draws a player sprite with its top left corner at screen (x, y)
+func (g *Game) drawNinja(x, y, facing int, style tcell.Style) {
+	if facing == 1 { // Facing right
 		g.screen.SetContent(x, y, '~', nil, style)
 		g.screen.SetContent(x+1, y, '0', nil, style)
 		g.screen.SetContent(x, y+1, '(', nil, style)
@@ -236,7 +385,7 @@ func (g *Game) drawPlayer() {
 }
 
 func (g *Game) drawEnemy(e *Enemy) {
-	x := int(e.Pos.X)
+	x := int(e.Pos.X) - g.cameraX
 	y := int(e.Pos.Y)
 
 	// Use light gray color for all enemies
@@ -265,7 +414,7 @@ func (g *Game) drawEnemy(e *Enemy) {
 }
 
 func (g *Game) drawCorpse(c *Corpse) {
-	x := int(c.Pos.X)
+	x := int(c.Pos.X) - g.cameraX
 	y := int(c.Pos.Y)
 	style := tcell.StyleDefault.Foreground(tcell.ColorLightGray)
 	// Draw a headless, shuffling corpse
@@ -299,12 +448,12 @@ func (g *Game) drawCorpse(c *Corpse) {
 }
 
 func (g *Game) drawProjectile(p *Projectile) {
-	x := int(p.Pos.X)
+	x := int(p.Pos.X) - g.cameraX
 	y := int(p.Pos.Y)
 
 	// Use cyan color for enemy projectiles, yellow for player projectiles
 	var style tcell.Style
-	if p.IsEnemy {
+	if p.Owner == ownerEnemy {
 		cyanColor := tcell.Color(51) // Cyan in 256-color palette
 		style = tcell.StyleDefault.Foreground(cyanColor)
 	} else {
@@ -325,13 +474,17 @@ func (g *Game) drawGround() {
 	// Dark gray color (using color index 8 from standard palette, or 240 for lighter dark gray)
 	darkGray := tcell.Color(240) // Dark gray in 256-color palette
 
-	for x := 0; x < g.width; x++ {
+	for screenX := 0; screenX < g.width; screenX++ {
+		x := screenX + g.cameraX // World column
+		if g.isPit(x) {
+			continue
+		}
 		// Use blood color mode if tile is marked, otherwise dark gray
 		if _, isMarked := g.redGroundTiles[x]; isMarked {
 			// If blood is off, use dark gray
 			if g.bloodColorMode == 3 {
 				style := tcell.StyleDefault.Foreground(darkGray)
-				g.screen.SetContent(x, g.groundY, groundChar, nil, style)
+				g.screen.SetContent(screenX, g.groundY, groundChar, nil, style)
 			} else {
 				var style tcell.Style
 
@@ -356,11 +509,11 @@ func (g *Game) drawGround() {
 					style = tcell.StyleDefault.Foreground(tcell.ColorRed)
 				}
 
-				g.screen.SetContent(x, g.groundY, groundChar, nil, style)
+				g.screen.SetContent(screenX, g.groundY, groundChar, nil, style)
 			}
 		} else {
 			style := tcell.StyleDefault.Foreground(darkGray)
-			g.screen.SetContent(x, g.groundY, groundChar, nil, style)
+			g.screen.SetContent(screenX, g.groundY, groundChar, nil, style)
 		}
 	}
 }
@@ -375,8 +528,8 @@ func (g *Game) drawPlatforms() {
 		endX := int(platform.X + platform.Width)
 		y := int(platform.Y)
 
-		for x := startX; x < endX && x < g.width; x++ {
-			if x >= 0 {
+		for x := startX; x < endX && x < g.cameraX+g.width; x++ {
+			if x >= g.cameraX {
 				// Check if this tile is marked red
 				key := platformIndex*10000 + x
 				var style tcell.Style
@@ -408,7 +561,7 @@ func (g *Game) drawPlatforms() {
 				} else {
 					style = defaultStyle
 				}
-				g.screen.SetContent(x, y, platformChar, nil, style)
+				g.screen.SetContent(x-g.cameraX, y, platformChar, nil, style)
 			}
 		}
 	}
@@ -416,12 +569,46 @@ func (g *Game) drawPlatforms() {
 
 func (g *Game) drawScore() {
 	style := tcell.StyleDefault.Foreground(tcell.ColorWhite)
-	scoreText := fmt.Sprintf("Score: %d", g.score)
-	for i, r := range scoreText {
-		g.screen.SetContent(i, 0, r, nil, style)
+	if g.match != nil {
+		g.drawMatchScore()
+		return
+	}
+
+	x := 0
+	if len(g.players) == 1 {
+		x = g.drawText(x, 0, fmt.Sprintf("Score: %d", g.players[0].Score), style)
+	} else {
+		// Each player's score and lives in their own color
+		for i := range g.players {
+			p := &g.players[i]
+			text := fmt.Sprintf("P%d: %d %s  ", i+1, p.Score, strings.Repeat("♥", p.Lives))
+			x = g.drawText(x, 0, text, tcell.StyleDefault.Foreground(p.Color))
+		}
+	}
+	if g.goalX > 0 {
+		// Distance left to run on stage levels, for whoever is furthest along
+		remaining := int(g.goalX)
+		for i := range g.players {
+			if p := &g.players[i]; !p.Dead {
+				remaining = min(remaining, int(g.goalX-p.Pos.X-float64(p.Width)))
+			}
+		}
+		if remaining < 0 {
+			remaining = 0
+		}
+		g.drawText(x, 0, fmt.Sprintf("  Goal: %d >", remaining), style)
 	}
 }
 
+// drawText draws a line of text and returns the column after it
+func (g *Game) drawText(x, y int, text string, style tcell.Style) int {
+	for _, r := range text {
+		g.screen.SetContent(x, y, r, nil, style)
+		x++
+	}
+	return x
+}
+
 func (g *Game) drawMenu() {
 	// Title "GNinja" in green, centered
 	title := "GNinja"
@@ -434,7 +621,7 @@ func (g *Game) drawMenu() {
 	}
 
 	// "Press Space to start" below title
-	startText := "Press Space to start"
+	startText := "Press Space to start, 2 for co-op"
 	startX := (g.width - len(startText)) / 2
 	startY := titleY + 2
 
@@ -442,6 +629,13 @@ func (g *Game) drawMenu() {
 		g.screen.SetContent(startX+i, startY, r, nil, tcell.StyleDefault)
 	}
 
+	if g.menuNote != "" {
+		g.drawText((g.width-len(g.menuNote))/2, startY+1, g.menuNote, tcell.StyleDefault.Foreground(tcell.ColorRed))
+	} else if g.hasSave {
+		continueText := "Press C to continue your saved run"
+		g.drawText((g.width-len(continueText))/2, startY+1, continueText, greenStyle)
+	}
+
 	// Show current blood color mode
 	var modeText string
 	switch g.bloodColorMode {
@@ -460,12 +654,69 @@ func (g *Game) drawMenu() {
 	for i, r := range modeText {
 		g.screen.SetContent(modeX+i, modeY, r, nil, tcell.StyleDefault)
 	}
+
+	// Show selected level
+	levelName := g.levels[g.levelIndex].name
+	if g.level.Seed != 0 {
+		levelName = fmt.Sprintf("%s #%d", levelName, g.level.Seed)
+	}
+	levelText := fmt.Sprintf("Level: %s (Press L to choose, E to edit)", levelName)
+	levelX := (g.width - len(levelText)) / 2
+	levelY := modeY + 1
+
+	for i, r := range levelText {
+		g.screen.SetContent(levelX+i, levelY, r, nil, tcell.StyleDefault)
+	}
+
+	// Versus settings
+	enemiesText := "no enemies"
+	if g.versusEnemies {
+		enemiesText = "enemies"
+	}
+	versusText := fmt.Sprintf("Versus: first to %d, %s (Press V to duel, R/N to change)", g.versusRounds, enemiesText)
+	g.drawText((g.width-len(versusText))/2, levelY+1, versusText, tcell.StyleDefault)
+
+	if g.ghostDir != "" {
+		ghostText := "Ghost: Off (Press G to race your best run)"
+		if g.ghostsOn {
+			ghostText = "Ghost: On (Press G to turn off)"
+		}
+		g.drawText((g.width-len(ghostText))/2, levelY+2, ghostText, tcell.StyleDefault)
+	}
+
+	difficultyText := fmt.Sprintf("Difficulty: %s (Press D to change)", g.difficulty.Name)
+	g.drawText((g.width-len(difficultyText))/2, levelY+3, difficultyText, tcell.StyleDefault)
+
+	statsText := "Press S for lifetime stats, A for achievements"
+	g.drawText((g.width-len(statsText))/2, levelY+4, statsText, tcell.StyleDefault)
+
+	if g.host != nil {
+		hostText := fmt.Sprintf("Hosting on %s: %d joined", g.host.listener.Addr(), g.host.clientCount())
+		if n := g.host.spectatorCount(); n > 0 {
+			hostText += fmt.Sprintf(", %d watching", n)
+		}
+		g.drawText((g.width-len(hostText))/2, levelY+6, hostText, greenStyle)
+	}
 }
 
 func (g *Game) drawGameOver() {
 	// Draw game over text at the top center, not covering the game
 	style := tcell.StyleDefault.Foreground(tcell.ColorRed).Bold(true)
 	text := "GAME OVER"
+	if cause, ok := causeText[g.lastDeath]; ok {
+		text += ": " + strings.ToUpper(cause)
+	}
+	if g.match != nil && g.match.Winner >= 0 {
+		style = tcell.StyleDefault.Foreground(g.players[g.match.Winner].Color).Bold(true)
+		text = fmt.Sprintf("P%d WINS THE MATCH %s", g.match.Winner+1, g.match.winsText())
+	}
+	if g.stageClear {
+		style = tcell.StyleDefault.Foreground(tcell.ColorGreen).Bold(true)
+		text = fmt.Sprintf("STAGE CLEAR in %.1fs", g.runTime.Seconds())
+	}
+	if result := g.ghostResult(); result != "" {
+		text += fmt.Sprintf(" (%s)", result)
+	}
 	startX := (g.width - len(text)) / 2
 	startY := 2 // Near the top, below score
 
@@ -474,10 +725,26 @@ func (g *Game) drawGameOver() {
 	}
 
 	style = tcell.StyleDefault.Foreground(tcell.ColorWhite)
+	if g.match == nil && g.client == nil && g.watching == "" {
+		summary := g.runSummary()
+		g.drawText((g.width-len(summary))/2, startY+1, summary, style)
+	}
 	instructions := []string{
 		"Press ENTER to restart",
 		"Press ESC to exit",
 	}
+	if g.client != nil {
+		instructions = []string{
+			"Waiting for the host",
+			"Press ESC to leave",
+		}
+	}
+	if g.watching != "" {
+		instructions = []string{
+			"Waiting for the next game",
+			"Press ESC to leave",
+		}
+	}
 
 	for i, line := range instructions {
 		lineX := (g.width - len(line)) / 2
@@ -485,21 +752,42 @@ func (g *Game) drawGameOver() {
 			g.screen.SetContent(lineX+j, startY+2+i, r, nil, style)
 		}
 	}
+
+	if len(g.players) > 1 && g.match == nil {
+		// Final co-op scores, one player per color
+		texts := make([]string, len(g.players))
+		width := 0
+		for i := range g.players {
+			texts[i] = fmt.Sprintf("P%d: %d  ", i+1, g.players[i].Score)
+			width += len(texts[i])
+		}
+		x := (g.width - width + 2) / 2
+		for i := range g.players {
+			x = g.drawText(x, startY+3+len(instructions), texts[i], tcell.StyleDefault.Foreground(g.players[i].Color))
+		}
+	}
+
+	if g.match == nil && g.client == nil {
+		g.drawHighScores(startY + 5 + len(instructions))
+	}
 }
 
-func (g *Game) updatePlayer(deltaTime float64) {
-	groundSpeed := 50.0 // pixels per second - reduced ground movement speed
-	airSpeed := 45.0    // pixels per second - improved air control (closer to ground speed)
-	gravity := 300.0    // pixels per second squared
-	jumpSpeed := -85.0  // upward velocity for jump (reduced for lower jump)
+func (g *Game) updatePlayer(p *Player, deltaTime float64) {
+	if p.Dead {
+		return
+	}
+
+	groundSpeed := g.tuning.GroundSpeed
+	airSpeed := g.tuning.AirSpeed
+	gravity := g.tuning.Gravity
+	jumpSpeed := g.tuning.JumpSpeed
 	groundY := float64(g.groundY - PlayerHeight)
 
-	now := time.Now()
-	keyTimeout := 150 * time.Millisecond // Increased timeout for smoother, more responsive controls
+	now := g.now
 
 	// Choose speed based on whether player is on ground or in air
 	speed := groundSpeed
-	if !g.player.OnGround {
+	if !p.OnGround {
 		speed = airSpeed // Improved air control
 	}
 
@@ -507,105 +795,116 @@ func (g *Game) updatePlayer(deltaTime float64) {
 	// Separate facing direction from movement direction
 	leftPressed := false
 	rightPressed := false
-	if lastPress, ok := g.keys[tcell.KeyLeft]; ok && now.Sub(lastPress) < keyTimeout {
+	if p.holding(ActionLeft, now) {
 		leftPressed = true
-		g.player.Facing = -1 // Update facing immediately when key is pressed
+		p.Facing = -1 // Update facing immediately when key is pressed
 	}
-	if lastPress, ok := g.keys[tcell.KeyRight]; ok && now.Sub(lastPress) < keyTimeout {
+	if p.holding(ActionRight, now) {
 		rightPressed = true
-		g.player.Facing = 1 // Update facing immediately when key is pressed
+		p.Facing = 1 // Update facing immediately when key is pressed
 	}
 
 	// Update movement direction - only move if key is actively held
 	// Movement happens continuously while key is held, not just on press
-	g.player.MoveDir = 0
+	p.MoveDir = 0
 	if leftPressed {
-		g.player.MoveDir = -1
-		g.player.Pos.X -= speed * deltaTime
+		p.MoveDir = -1
+		p.Pos.X -= speed * deltaTime
 	}
 	if rightPressed {
-		g.player.MoveDir = 1
-		g.player.Pos.X += speed * deltaTime
+		p.MoveDir = 1
+		p.Pos.X += speed * deltaTime
 	}
 
 	// Handle jumping - improved diagonal jumps with coyote time
 	coyoteTime := 100 * time.Millisecond // Allow jumping slightly after leaving ground/platform
-	canJump := g.player.OnGround || g.player.OnPlatform ||
-		(!g.player.OnGround && !g.player.OnPlatform && time.Since(g.player.LastOnGroundTime) < coyoteTime)
+	canJump := p.OnGround || p.OnPlatform ||
+		(!p.OnGround && !p.OnPlatform && g.now.Sub(p.LastOnGroundTime) < coyoteTime)
 
-	if lastPress, ok := g.keys[tcell.KeyUp]; ok && now.Sub(lastPress) < keyTimeout {
+	if p.holding(ActionJump, now) {
 		if canJump {
-			g.player.Vel.Y = jumpSpeed
-			g.player.OnGround = false
-			g.player.OnPlatform = false
+			if p.Vel.Y >= 0 {
+				p.Jumps++ // Not while still rising from a jump in coyote time
+			}
+			p.Vel.Y = jumpSpeed
+			p.OnGround = false
+			p.OnPlatform = false
 		}
 	}
 
 	// Apply gravity if not on ground
-	if !g.player.OnGround {
-		g.player.Vel.Y += gravity * deltaTime
+	if !p.OnGround {
+		p.Vel.Y += gravity * deltaTime
 	}
 
 	// Update vertical position
-	g.player.Pos.Y += g.player.Vel.Y * deltaTime
+	p.Pos.Y += p.Vel.Y * deltaTime
 
 	// Check platform collisions first
 	onPlatform := false
 	platformTopY := 0.0
 	for _, platform := range g.platforms {
 		// Check if player is above platform and within horizontal bounds
-		if g.player.Pos.X < platform.X+platform.Width &&
-			g.player.Pos.X+float64(g.player.Width) > platform.X &&
-			g.player.Pos.Y < platform.Y+platform.Height &&
-			g.player.Pos.Y+float64(g.player.Height) > platform.Y {
+		if p.Pos.X < platform.X+platform.Width &&
+			p.Pos.X+float64(p.Width) > platform.X &&
+			p.Pos.Y < platform.Y+platform.Height &&
+			p.Pos.Y+float64(p.Height) > platform.Y {
 			// Player is colliding with platform
 			// If falling down onto platform, land on top
-			if g.player.Vel.Y > 0 && g.player.Pos.Y < platform.Y {
-				platformTopY = platform.Y - float64(g.player.Height)
-				g.player.Pos.Y = platformTopY
-				g.player.Vel.Y = 0
-				g.player.OnGround = false
-				g.player.OnPlatform = true
-				g.player.LastOnGroundTime = time.Now()
+			if p.Vel.Y > 0 && p.Pos.Y < platform.Y {
+				platformTopY = platform.Y - float64(p.Height)
+				p.Pos.Y = platformTopY
+				p.Vel.Y = 0
+				p.OnGround = false
+				p.OnPlatform = true
+				p.LastOnGroundTime = g.now
 				onPlatform = true
 				break
 			}
 		}
 	}
 
+	// A player who has dropped into a pit can't walk back out of it
+	if p.Pos.Y > groundY {
+		g.confineToPit(&p.Pos.X, p.Width)
+	}
+
 	// Check ground collision only if not on a platform
 	if !onPlatform {
-		if g.player.Pos.Y >= groundY {
-			g.player.Pos.Y = groundY
-			if g.player.Vel.Y > 0 {
-				g.player.Vel.Y = 0
-				g.player.OnGround = true
-				g.player.OnPlatform = false
-				g.player.LastOnGroundTime = time.Now()
+		if p.Pos.Y >= groundY && !g.overPit(p.Pos.X, p.Width) {
+			p.Pos.Y = groundY
+			if p.Vel.Y > 0 {
+				p.Vel.Y = 0
+				p.OnGround = true
+				p.OnPlatform = false
+				p.LastOnGroundTime = g.now
 			}
 		} else {
 			// Check if player is falling through platforms (not on top)
-			if g.player.OnGround || g.player.OnPlatform {
-				g.player.LastOnGroundTime = time.Now()
+			if p.OnGround || p.OnPlatform {
+				p.LastOnGroundTime = g.now
 			}
-			g.player.OnGround = false
-			g.player.OnPlatform = false
+			p.OnGround = false
+			p.OnPlatform = false
 		}
 	}
 
+	// Co-op players share the screen, so nobody can walk off it
+	if len(g.players) > 1 {
+		p.Pos.X = math.Max(p.Pos.X, float64(g.cameraX))
+		p.Pos.X = math.Min(p.Pos.X, float64(g.cameraX+g.width-p.Width))
+	}
+
 	// Keep player in bounds horizontally
-	if g.player.Pos.X < 0 {
-		g.player.Pos.X = 0
+	if p.Pos.X < 0 {
+		p.Pos.X = 0
 	}
-	if g.player.Pos.X+float64(g.player.Width) > float64(g.width) {
-		g.player.Pos.X = float64(g.width - g.player.Width)
+	if p.Pos.X+float64(p.Width) > float64(g.worldWidth) {
+		p.Pos.X = float64(g.worldWidth - p.Width)
 	}
 }
 
 func (g *Game) updateProjectiles(deltaTime float64) {
-	playerProjectileSpeed := 200.0 // pixels per second
-	enemyProjectileSpeed := 80.0   // pixels per second - even slower for easier dodging
-
 	// When game over, remove all projectiles
 	if g.gameOver {
 		g.projectiles = make([]Projectile, 0)
@@ -617,19 +916,16 @@ func (g *Game) updateProjectiles(deltaTime float64) {
 			continue
 		}
 
-		// Use different speeds for player and enemy projectiles
-		speed := playerProjectileSpeed
-		if g.projectiles[i].IsEnemy {
-			speed = enemyProjectileSpeed
-		}
-
 		// Store previous position for swept collision detection
 		g.projectiles[i].PrevPos = g.projectiles[i].Pos
-		g.projectiles[i].Pos.X += float64(g.projectiles[i].Dir) * speed * deltaTime
+		vel := g.projectiles[i].velocity()
+		g.projectiles[i].Pos.X += vel.X * deltaTime
+		g.projectiles[i].Pos.Y += vel.Y * deltaTime
+		g.projectiles[i].Vel.Y += g.projectiles[i].Gravity * deltaTime // Lobbed shots fall
 		g.projectiles[i].Frame++
 
-		// Remove projectiles that go off screen
-		if g.projectiles[i].Pos.X < 0 || g.projectiles[i].Pos.X > float64(g.width) {
+		// Remove projectiles that go off screen or into the ground
+		if !g.inView(g.projectiles[i].Pos.X, 0) || g.projectiles[i].Pos.Y >= float64(g.groundY) {
 			g.projectiles[i].Active = false
 		}
 	}
@@ -645,22 +941,29 @@ func (g *Game) updateProjectiles(deltaTime float64) {
 }
 
 func (g *Game) updateEnemies(deltaTime float64) {
-	baseSpeed := 20.0  // pixels per second - slower
-	gravity := 300.0   // pixels per second squared (same as player)
-	jumpSpeed := -85.0 // Same as player jump speed
+	baseSpeed := g.difficulty.EnemySpeed // pixels per second - slower
+	gravity := g.tuning.Gravity          // pixels per second squared (same as player)
+	jumpSpeed := g.tuning.JumpSpeed
 	groundY := float64(g.groundY - EnemyHeight)
 
+	if !g.gameOver {
+		g.directEnemies()
+	}
+
 	for i := range g.enemies {
 		if !g.enemies[i].Active {
 			continue
 		}
 
 		speed := baseSpeed
-		minDistance := 30.0 // Minimum distance shooting enemies try to maintain
+		minDistance := g.enemies[i].Post // Distance a shooting enemy tries to keep
 
-		if g.gameOver {
+		// Go after whichever player is closest
+		target := g.nearestPlayer(g.enemies[i].Pos)
+
+		if g.gameOver || target == nil {
 			// When game over, make enemies walk off screen (toward nearest edge)
-			screenCenter := float64(g.width) / 2.0
+			screenCenter := float64(g.cameraX) + float64(g.width)/2.0
 			dx := g.enemies[i].Pos.X - screenCenter
 			if dx > 0 {
 				// Move right off screen
@@ -672,11 +975,34 @@ func (g *Game) updateEnemies(deltaTime float64) {
 				g.enemies[i].Pos.X -= speed * deltaTime
 			}
 		} else {
-			// Handle movement based on whether enemy can shoot
-			dx := g.player.Pos.X - g.enemies[i].Pos.X
+			// Find the way to the player's surface, then go straight for them
+			// once on it
+			routeX, jump, same := g.routeEnemy(&g.enemies[i], target)
+			if !same && g.holdsLine(&g.enemies[i], target) {
+				// Shooters that can hit the player from where they are stay put
+				routeX, jump = g.enemies[i].Pos.X, false
+			}
+			dx := target.Pos.X - g.enemies[i].Pos.X
 			distance := math.Abs(dx)
+			canJump := g.enemies[i].OnGround && g.now.Sub(g.enemies[i].JumpCooldown) > enemyJumpCooldown
+			if jump && !canJump {
+				routeX = g.enemies[i].Pos.X // Wait where the jump works from
+			}
 
-			if g.enemies[i].CanShoot {
+			if !same {
+				// Head for the next move on the route, stopping once there
+				if step := routeX - g.enemies[i].Pos.X; math.Abs(step) > speed*deltaTime {
+					if step > 0 {
+						g.enemies[i].Facing = 1
+						g.enemies[i].Pos.X += speed * deltaTime
+					} else {
+						g.enemies[i].Facing = -1
+						g.enemies[i].Pos.X -= speed * deltaTime
+					}
+				} else {
+					g.enemies[i].Pos.X = routeX
+				}
+			} else if g.enemies[i].CanShoot {
 				// Shooting enemies try to maintain minimum distance
 				if distance < minDistance {
 					// Too close, move away from player
@@ -698,6 +1024,12 @@ func (g *Game) updateEnemies(deltaTime float64) {
 					}
 				}
 				// If at good distance, don't move horizontally
+			} else if g.enemies[i].Role == roleHold && distance <= g.enemies[i].Post {
+				// Holding back for a pincer: wait, facing the player
+				g.enemies[i].Facing = 1
+				if dx < 0 {
+					g.enemies[i].Facing = -1
+				}
 			} else {
 				// Non-shooting enemies always move towards player
 				if dx > 0 {
@@ -711,51 +1043,14 @@ func (g *Game) updateEnemies(deltaTime float64) {
 
 			// Handle enemy shooting
 			if g.enemies[i].CanShoot && !g.gameOver {
-				now := time.Now()
-				if g.enemies[i].LastShot.IsZero() {
-					// First shot - set initial delay
-					g.enemies[i].NextShotDelay = time.Duration(1.0+rand.Float64()*2.0) * time.Second
-					g.enemies[i].LastShot = now
-				} else if now.Sub(g.enemies[i].LastShot) >= g.enemies[i].NextShotDelay {
-					// Time to shoot
-					p := Projectile{
-						Pos:     Vec2{X: g.enemies[i].Pos.X + float64(g.enemies[i].Width/2), Y: g.enemies[i].Pos.Y + float64(g.enemies[i].Height/2)},
-						PrevPos: Vec2{X: g.enemies[i].Pos.X + float64(g.enemies[i].Width/2), Y: g.enemies[i].Pos.Y + float64(g.enemies[i].Height/2)},
-						Dir:     g.enemies[i].Facing,
-						Active:  true,
-						Frame:   0,
-						IsEnemy: true,
-					}
-					g.projectiles = append(g.projectiles, p)
-					g.enemies[i].LastShot = now
-					// Set next shot delay (1-3 seconds)
-					g.enemies[i].NextShotDelay = time.Duration(1.0+rand.Float64()*2.0) * time.Second
-				}
+				g.enemyShoot(&g.enemies[i], target)
 			}
 
-			// Check if enemy should jump to reach player or platform
-			// Jump if player is significantly higher and enemy is on ground
-			dy := g.player.Pos.Y - g.enemies[i].Pos.Y
-			if g.enemies[i].OnGround && dy < -10.0 && time.Since(g.enemies[i].JumpCooldown) > 1*time.Second {
-				// Player is above, try to jump
+			// Jump when the route says to
+			if jump && canJump {
 				g.enemies[i].Vel.Y = jumpSpeed
 				g.enemies[i].OnGround = false
-				g.enemies[i].JumpCooldown = time.Now()
-			} else {
-				// Check if there's a platform nearby that the enemy should jump to
-				for _, platform := range g.platforms {
-					platformY := platform.Y - float64(EnemyHeight)
-					// If platform is above enemy and within reasonable distance
-					if platformY < g.enemies[i].Pos.Y-5.0 &&
-						math.Abs(g.enemies[i].Pos.X-(platform.X+platform.Width/2)) < 30.0 &&
-						g.enemies[i].OnGround &&
-						time.Since(g.enemies[i].JumpCooldown) > 1*time.Second {
-						g.enemies[i].Vel.Y = jumpSpeed
-						g.enemies[i].OnGround = false
-						g.enemies[i].JumpCooldown = time.Now()
-						break
-					}
-				}
+				g.enemies[i].JumpCooldown = g.now
 			}
 		}
 
@@ -788,9 +1083,13 @@ func (g *Game) updateEnemies(deltaTime float64) {
 			}
 		}
 
+		if g.enemies[i].Pos.Y > groundY {
+			g.confineToPit(&g.enemies[i].Pos.X, g.enemies[i].Width)
+		}
+
 		// Check ground collision only if not on a platform
 		if !onPlatform {
-			if g.enemies[i].Pos.Y >= groundY {
+			if g.enemies[i].Pos.Y >= groundY && !g.overPit(g.enemies[i].Pos.X, g.enemies[i].Width) {
 				g.enemies[i].Pos.Y = groundY
 				if g.enemies[i].Vel.Y > 0 {
 					g.enemies[i].Vel.Y = 0
@@ -801,8 +1100,10 @@ func (g *Game) updateEnemies(deltaTime float64) {
 			}
 		}
 
-		// Remove enemies that go off screen
-		if g.enemies[i].Pos.X < -float64(EnemyWidth) || g.enemies[i].Pos.X > float64(g.width) {
+		// Remove enemies that leave the world, or the screen once the game is over
+		if g.enemies[i].Pos.X < -float64(EnemyWidth) || g.enemies[i].Pos.X > float64(g.worldWidth) ||
+			g.enemies[i].Pos.Y > float64(g.height) ||
+			(g.gameOver && !g.inView(g.enemies[i].Pos.X, float64(EnemyWidth))) {
 			g.enemies[i].Active = false
 		}
 	}
@@ -816,63 +1117,56 @@ func (g *Game) updateEnemies(deltaTime float64) {
 	}
 	g.enemies = active
 
-	// Don't spawn enemies if game is over
-	if g.gameOver {
+	// Don't spawn enemies if game is over, or in a versus match without them
+	if g.gameOver || (g.match != nil && !g.match.Enemies) {
 		return
 	}
 
-	// Calculate spawn rate based on enemies defeated (increases every 5 enemies)
-	baseSpawnRate := 0.008                                    // 0.8% base chance per frame (lowered from 2%)
-	spawnRateIncrease := float64(g.enemiesDefeated/5) * 0.005 // +0.5% per 5 enemies defeated
-	spawnRate := baseSpawnRate + spawnRateIncrease
-	if spawnRate > 0.05 {
-		spawnRate = 0.05 // Cap at 5% max
+	// The director sets how fast enemies come
+	spawnRate := g.direct()
+	if spawnRate != g.spawnRate {
+		g.spawnRate = spawnRate
+		g.events.publish(SpawnRateChanged{Rate: spawnRate, Phase: phaseNames[g.director.Phase], EnemiesDefeated: g.enemiesDefeated})
 	}
 
 	// Spawn new enemies randomly
-	if rand.Float64() < spawnRate {
-		var e Enemy
-
-		// Every other enemy can shoot
-		canShoot := (g.enemySpawnCounter%2 == 1)
-		g.enemySpawnCounter++
-
-		if rand.Float64() < 0.5 {
-			// Spawn from left
-			e = Enemy{
-				Pos:           Vec2{X: -float64(EnemyWidth), Y: float64(g.groundY - EnemyHeight)},
-				Vel:           Vec2{X: 0, Y: 0},
-				Facing:        1,
-				Width:         EnemyWidth,
-				Height:        EnemyHeight,
-				Active:        true,
-				CanShoot:      canShoot,
-				LastShot:      time.Time{},
-				NextShotDelay: 0,
-				OnGround:      true,
-				JumpCooldown:  time.Time{},
-			}
-		} else {
-			// Spawn from right
-			e = Enemy{
-				Pos:           Vec2{X: float64(g.width), Y: float64(g.groundY - EnemyHeight)},
-				Vel:           Vec2{X: 0, Y: 0},
-				Facing:        -1,
-				Width:         EnemyWidth,
-				Height:        EnemyHeight,
-				Active:        true,
-				CanShoot:      canShoot,
-				LastShot:      time.Time{},
-				NextShotDelay: 0,
-				OnGround:      true,
-				JumpCooldown:  time.Time{},
+	if g.rng.Float64() < spawnRate {
+		kind := g.pickEnemyKind()
+		spawn := g.pickEnemySpawn()
+
+		facing := spawn.Facing
+		if facing == 0 {
+			// Face the nearest player
+			facing = 1
+			if p := g.nearestPlayer(Vec2{X: spawn.X, Y: spawn.Y}); p != nil && p.Pos.X < spawn.X {
+				facing = -1
 			}
 		}
+
+		g.enemySerial++
+		e := Enemy{
+			ID:            g.enemySerial,
+			Pos:           Vec2{X: spawn.X, Y: spawn.Y},
+			Vel:           Vec2{X: 0, Y: 0},
+			Facing:        facing,
+			Width:         EnemyWidth,
+			Height:        EnemyHeight,
+			Active:        true,
+			Kind:          kind,
+			CanShoot:      kind == EnemyShooter,
+			Pattern:       g.shooterPattern(kind),
+			LastShot:      time.Time{},
+			NextShotDelay: 0,
+			OnGround:      spawn.Y >= float64(g.groundY-EnemyHeight),
+			JumpCooldown:  time.Time{},
+			NavTo:         -1,
+		}
 		g.enemies = append(g.enemies, e)
+		g.events.publish(EnemySpawned{EnemyID: e.ID, Kind: e.Kind, Pos: e.Pos})
 	}
 }
 
-func (g *Game) createPlayerDeathParticles() {
+func (g *Game) createPlayerDeathParticles(p *Player) {
 	// Assign a unique enemy ID for player particles (use 0 for player)
 	enemyID := 0
 
@@ -885,7 +1179,7 @@ func (g *Game) createPlayerDeathParticles() {
 
 	var pieces []SpritePiece
 
-	if g.player.Facing == 1 { // Facing right
+	if p.Facing == 1 { // Facing right
 		pieces = []SpritePiece{
 			{'~', 0, 0},
 			{'0', 1, 0},
@@ -909,10 +1203,10 @@ func (g *Game) createPlayerDeathParticles() {
 
 	// Create particles from actual player pieces
 	for _, piece := range pieces {
-		// 20% chance to be red
-		isRed := rand.Float64() < 0.2
-		// 30% chance to fall through ground
-		fallsThrough := rand.Float64() < 0.3
+		// Some pieces are bloodied
+		isRed := rand.Float64() < g.tuning.RedChance
+		// and some fall through the ground
+		fallsThrough := rand.Float64() < g.tuning.FallThroughChance
 
 		// Check if this is the head piece ('0')
 		isHead := (piece.char == '0')
@@ -920,8 +1214,8 @@ func (g *Game) createPlayerDeathParticles() {
 		rollDistance := 0.0
 		rollSpeed := 0.0
 
-		// Head piece has 5% chance to roll after bouncing
-		if isHead && rand.Float64() < 0.05 {
+		// The head sometimes rolls after bouncing
+		if isHead && rand.Float64() < g.tuning.HeadRollChance {
 			isRolling = true
 			rollDistance = 20.0 + rand.Float64()*30.0 // Roll 20-50 pixels
 			rollSpeed = 40.0 + rand.Float64()*20.0    // 40-60 pixels/sec rolling speed
@@ -941,7 +1235,7 @@ func (g *Game) createPlayerDeathParticles() {
 		angularVel := (rand.Float64() - 0.5) * 360.0 // -180 to 180 degrees per second
 
 		particle := DeathParticle{
-			Pos:                     Vec2{X: g.player.Pos.X + float64(piece.x), Y: g.player.Pos.Y + float64(piece.y)},
+			Pos:                     Vec2{X: p.Pos.X + float64(piece.x), Y: p.Pos.Y + float64(piece.y)},
 			Vel:                     Vec2{X: velX, Y: velY},
 			Char:                    piece.char,
 			OnGround:                false,
@@ -960,7 +1254,7 @@ func (g *Game) createPlayerDeathParticles() {
 			BouncedFromPlatform:     false,
 			WasOnPlatform:           false,
 			HasSplattedFromPlatform: false,
-			LastBloodEmit:           time.Now(),
+			LastBloodEmit:           g.now,
 			HasHitGround:            false,
 		}
 		g.deathParticles = append(g.deathParticles, particle)
@@ -972,11 +1266,12 @@ func (g *Game) createPlayerDeathParticles() {
 	}
 }
 
-func (g *Game) createDeathParticles(e *Enemy) {
-	// 5% chance to decapitate: head pops off and body becomes mobile corpse
-	if rand.Float64() < 0.1 {
+// createDeathParticles blows an enemy apart, reporting whether its head came off
+func (g *Game) createDeathParticles(e *Enemy) bool {
+	// Sometimes the head pops off and the body becomes a mobile corpse
+	if rand.Float64() < g.tuning.DecapChance {
 		g.createDecap(e)
-		return
+		return true
 	}
 	// Assign a unique enemy ID for this enemy's particles
 	enemyID := g.nextEnemyID
@@ -1026,10 +1321,10 @@ func (g *Game) createDeathParticles(e *Enemy) {
 
 	// Create particles from actual enemy pieces
 	for _, piece := range pieces {
-		// 20% chance to be red
-		isRed := rand.Float64() < 0.2
-		// 30% chance to fall through ground
-		fallsThrough := rand.Float64() < 0.3
+		// Some pieces are bloodied
+		isRed := rand.Float64() < g.tuning.RedChance
+		// and some fall through the ground
+		fallsThrough := rand.Float64() < g.tuning.FallThroughChance
 
 		// Check if this is the head piece ('O')
 		isHead := (piece.char == 'O')
@@ -1037,8 +1332,8 @@ func (g *Game) createDeathParticles(e *Enemy) {
 		rollDistance := 0.0
 		rollSpeed := 0.0
 
-		// Head piece has 5% chance to roll after bouncing
-		if isHead && rand.Float64() < 0.05 {
+		// The head sometimes rolls after bouncing
+		if isHead && rand.Float64() < g.tuning.HeadRollChance {
 			isRolling = true
 			rollDistance = 20.0 + rand.Float64()*30.0 // Roll 20-50 pixels
 			rollSpeed = 40.0 + rand.Float64()*20.0    // 40-60 pixels/sec rolling speed
@@ -1077,7 +1372,7 @@ func (g *Game) createDeathParticles(e *Enemy) {
 			BouncedFromPlatform:     false,
 			WasOnPlatform:           wasOnPlatform,
 			HasSplattedFromPlatform: false,
-			LastBloodEmit:           time.Now(),
+			LastBloodEmit:           g.now,
 			HasHitGround:            false,
 		}
 		g.deathParticles = append(g.deathParticles, particle)
@@ -1088,7 +1383,7 @@ func (g *Game) createDeathParticles(e *Enemy) {
 		intensity := math.Min(initialSpeed/80.0, 1.0) // Scale intensity by speed
 		g.emitBloodFromParticle(&g.deathParticles[len(g.deathParticles)-1], intensity, true)
 	}
-
+	return false
 }
 
 // createDeathParticlesAt splits a body at a given position/facing using the provided enemyID
@@ -1122,10 +1417,10 @@ func (g *Game) createDeathParticlesAt(pos Vec2, facing int, enemyID int, wasOnPl
 	}
 
 	for _, piece := range pieces {
-		// 20% chance to be red
-		isRed := rand.Float64() < 0.2
-		// 30% chance to fall through ground
-		fallsThrough := rand.Float64() < 0.3
+		// Some pieces are bloodied
+		isRed := rand.Float64() < g.tuning.RedChance
+		// and some fall through the ground
+		fallsThrough := rand.Float64() < g.tuning.FallThroughChance
 
 		// Check if this is the head piece
 		isHead := (piece.char == 'O')
@@ -1134,7 +1429,7 @@ func (g *Game) createDeathParticlesAt(pos Vec2, facing int, enemyID int, wasOnPl
 		rollSpeed := 0.0
 
 		// Head piece may roll if it lands
-		if isHead && rand.Float64() < 0.05 {
+		if isHead && rand.Float64() < g.tuning.HeadRollChance {
 			isRolling = true
 			rollDistance = 20.0 + rand.Float64()*30.0
 			rollSpeed = 40.0 + rand.Float64()*20.0
@@ -1170,7 +1465,7 @@ func (g *Game) createDeathParticlesAt(pos Vec2, facing int, enemyID int, wasOnPl
 			BouncedFromPlatform:     false,
 			WasOnPlatform:           wasOnPlatform,
 			HasSplattedFromPlatform: false,
-			LastBloodEmit:           time.Now(),
+			LastBloodEmit:           g.now,
 			HasHitGround:            false,
 		}
 		g.deathParticles = append(g.deathParticles, particle)
@@ -1238,7 +1533,7 @@ func (g *Game) createDecap(e *Enemy) {
 		IsRolling:    isRolling,
 		RollDistance: rollDistance,
 		RollSpeed:    rollSpeed,
-		LastBloodEmit: time.Now(),
+		LastBloodEmit: g.now,
 	}
 	g.deathParticles = append(g.deathParticles, head)
 	// Emit an initial burst from the head pop
@@ -1259,11 +1554,11 @@ func (g *Game) createDecap(e *Enemy) {
 		OnGround:      false,
 		Active:        true,
 		EnemyID:       enemyID,
-		EndTime:       time.Now().Add(duration),
+		EndTime:       g.now.Add(duration),
 		MoveDir:       moveDir,
-		LastDirChange: time.Now(),
+		LastDirChange: g.now,
 		DirDuration:   time.Duration(200+rand.Intn(600)) * time.Millisecond,
-		LastBloodEmit: time.Now(),
+		LastBloodEmit: g.now,
 		WasOnPlatform: wasOnPlatform,
 	}
 	g.corpses = append(g.corpses, corp)
@@ -1275,8 +1570,8 @@ func (g *Game) updateCorpses(deltaTime float64) {
 		return
 	}
 
-	now := time.Now()
-	gravity := 300.0
+	now := g.now
+	gravity := g.tuning.Gravity
 	groundY := float64(g.groundY - EnemyHeight)
 	for i := range g.corpses {
 		c := &g.corpses[i]
@@ -1323,9 +1618,13 @@ func (g *Game) updateCorpses(deltaTime float64) {
 			}
 		}
 
+		if c.Pos.Y > groundY {
+			g.confineToPit(&c.Pos.X, EnemyWidth)
+		}
+
 		if !onPlatform {
 			// Ground collision
-			if c.Pos.Y >= groundY {
+			if c.Pos.Y >= groundY && !g.overPit(c.Pos.X, EnemyWidth) {
 				c.Pos.Y = groundY
 				if c.Vel.Y > 0 {
 					c.Vel.Y = 0
@@ -1341,8 +1640,8 @@ func (g *Game) updateCorpses(deltaTime float64) {
 			c.Pos.X = 0
 			c.MoveDir = 1
 		}
-		if c.Pos.X > float64(g.width-EnemyWidth) {
-			c.Pos.X = float64(g.width-EnemyWidth)
+		if c.Pos.X > float64(g.worldWidth-EnemyWidth) {
+			c.Pos.X = float64(g.worldWidth - EnemyWidth)
 			c.MoveDir = -1
 		}
 
@@ -1384,7 +1683,7 @@ func (g *Game) isTileUnderPlatform(x int) bool {
 func (g *Game) markGroundRed(tileX int, enemyID int, wasOnPlatform bool) {
 	// Mark only the exact tile that was touched
 	// Skip if particle was on platform AND tile is under a platform
-	if tileX >= 0 && tileX < g.width {
+	if tileX >= 0 && tileX < g.worldWidth {
 		if !wasOnPlatform || !g.isTileUnderPlatform(tileX) {
 			g.redGroundTiles[tileX] = enemyID
 		}
@@ -1478,7 +1777,7 @@ func (g *Game) emitBloodFromParticle(p *DeathParticle, intensity float64, impact
 func (g *Game) updateDeathParticles(deltaTime float64) {
 	// Update any mobile corpses first so they can squirt blood and later break apart
 	g.updateCorpses(deltaTime)
-	gravity := 300.0                  // pixels per second squared (increased to ensure falling)
+	gravity := g.tuning.Gravity       // pixels per second squared
 	bounceDamping := 0.4              // Velocity reduction on bounce (reduced to make bounces weaker)
 	groundY := float64(g.groundY - 1) // Rest one row above ground
 
@@ -1506,7 +1805,7 @@ func (g *Game) updateDeathParticles(deltaTime float64) {
 
 		// Emit blood particles continuously from pieces in the air (following them)
 		if isInAir {
-			now := time.Now()
+			now := g.now
 			// Emit every 50-150ms while in the air (more frequent for faster pieces)
 			speed := math.Sqrt(p.Vel.X*p.Vel.X + p.Vel.Y*p.Vel.Y)
 			emitInterval := 150*time.Millisecond - time.Duration(speed*0.8)*time.Millisecond
@@ -1566,7 +1865,7 @@ func (g *Game) updateDeathParticles(deltaTime float64) {
 						p.Vel.Y = 0
 						if !p.OnGround {
 							p.OnGround = true
-							p.GroundTime = time.Now()
+							p.GroundTime = g.now
 							p.Vel.X = 0
 							p.WasOnPlatform = true // Mark that particle is on platform
 						}
@@ -1580,7 +1879,7 @@ func (g *Game) updateDeathParticles(deltaTime float64) {
 					if !p.OnGround {
 						p.OnGround = true
 						if p.GroundTime.IsZero() {
-							p.GroundTime = time.Now()
+							p.GroundTime = g.now
 						}
 						p.WasOnPlatform = true
 					}
@@ -1599,13 +1898,13 @@ func (g *Game) updateDeathParticles(deltaTime float64) {
 
 			// Check if 3 seconds have passed (only if not rolling and not player pieces)
 			// Player pieces (EnemyID 0) never disappear until reset
-			if p.EnemyID != 0 && !(p.IsHead && p.IsRolling && p.RollDistance > 0) && time.Since(p.GroundTime) >= 3*time.Second {
+			if p.EnemyID != 0 && !(p.IsHead && p.IsRolling && p.RollDistance > 0) && g.now.Sub(p.GroundTime) >= 3*time.Second {
 				p.Active = false
 			}
 		}
 
-		// Check ground collision only if not on a platform
-		if !onPlatform && p.Pos.Y >= groundY {
+		// Check ground collision only if not on a platform (particles fall into pits)
+		if !onPlatform && p.Pos.Y >= groundY && !g.isPit(int(p.Pos.X)) {
 			// If particle falls through, let it pass through and emit blood (only once)
 			if p.FallsThrough && p.Vel.Y > 0 && p.Bounces == 0 {
 				// Emit blood particles when falling through (impact)
@@ -1625,7 +1924,7 @@ func (g *Game) updateDeathParticles(deltaTime float64) {
 					intensity := math.Min(impactSpeed/60.0, 1.0)
 					g.emitBloodFromParticle(p, intensity, true)
 					p.HasHitGround = true
-					p.LastBloodEmit = time.Now()
+					p.LastBloodEmit = g.now
 				}
 
 				if p.Vel.Y > 0 {
@@ -1667,7 +1966,7 @@ func (g *Game) updateDeathParticles(deltaTime float64) {
 								p.BouncedFromPlatform = false // Reset flag
 							}
 							p.OnGround = true
-							p.GroundTime = time.Now()
+							p.GroundTime = g.now
 
 							// If this is a head piece that should roll, start rolling
 							if p.IsHead && p.IsRolling && p.RollDistance > 0 {
@@ -1686,7 +1985,7 @@ func (g *Game) updateDeathParticles(deltaTime float64) {
 					// Already settled on ground
 					if !p.OnGround {
 						p.OnGround = true
-						p.GroundTime = time.Now()
+						p.GroundTime = g.now
 
 						// If this is a head piece that should roll, start rolling
 						if p.IsHead && p.IsRolling && p.RollDistance > 0 {
@@ -1701,7 +2000,7 @@ func (g *Game) updateDeathParticles(deltaTime float64) {
 
 					// Check if 3 seconds have passed (only if not rolling and not player pieces)
 					// Player pieces (EnemyID 0) never disappear until reset
-					if p.EnemyID != 0 && !(p.IsHead && p.IsRolling && p.RollDistance > 0) && time.Since(p.GroundTime) >= 3*time.Second {
+					if p.EnemyID != 0 && !(p.IsHead && p.IsRolling && p.RollDistance > 0) && g.now.Sub(p.GroundTime) >= 3*time.Second {
 						p.Active = false
 					}
 				}
@@ -1724,7 +2023,7 @@ func (g *Game) updateDeathParticles(deltaTime float64) {
 			// Use current velocity instead of initial roll speed
 			currentSpeed := math.Abs(p.Vel.X)
 			if currentSpeed > 0.1 {
-				now := time.Now()
+				now := g.now
 				// Emit every 80-200ms while rolling (more frequent when faster)
 				emitInterval := 200*time.Millisecond - time.Duration(currentSpeed*1.5)*time.Millisecond
 				if emitInterval < 50*time.Millisecond {
@@ -1748,7 +2047,7 @@ func (g *Game) updateDeathParticles(deltaTime float64) {
 				p.RollDistance = 0
 				// Reset ground time for the 3-second timer (only for non-player pieces)
 				if p.EnemyID != 0 {
-					p.GroundTime = time.Now()
+					p.GroundTime = g.now
 				}
 			}
 		} else if p.OnGround && p.Vel.Y == 0 && !(p.IsHead && p.IsRolling && p.RollDistance > 0) {
@@ -1758,7 +2057,7 @@ func (g *Game) updateDeathParticles(deltaTime float64) {
 		}
 
 		// Remove if off screen
-		if p.Pos.X < -10 || p.Pos.X > float64(g.width)+10 {
+		if p.Pos.X < -10 || p.Pos.X > float64(g.worldWidth)+10 {
 			p.Active = false
 		}
 	}
@@ -1774,7 +2073,7 @@ func (g *Game) updateDeathParticles(deltaTime float64) {
 }
 
 func (g *Game) updateBloodParticles(deltaTime float64) {
-	gravity := 300.0 // pixels per second squared
+	gravity := g.tuning.Gravity // pixels per second squared
 	groundY := float64(g.groundY)
 
 	// When game over, remove all enemy blood particles but keep player blood particles
@@ -1813,7 +2112,7 @@ func (g *Game) updateBloodParticles(deltaTime float64) {
 				p.Pos.Y+1.0 > platform.Y {
 				// Blood particle hit platform - mark platform and remove particle
 				tileX := int(p.Pos.X)
-				if tileX >= 0 && tileX < g.width {
+				if tileX >= 0 && tileX < g.worldWidth {
 					g.markPlatformRed(platformIndex, tileX, p.EnemyID)
 				}
 				p.Active = false
@@ -1825,7 +2124,7 @@ func (g *Game) updateBloodParticles(deltaTime float64) {
 		// Only check ground collision if didn't hit platform
 		if !hitPlatform && p.Pos.Y >= groundY {
 			tileX := int(p.Pos.X)
-			if tileX >= 0 && tileX < g.width {
+			if tileX >= 0 && tileX < g.worldWidth && !g.isPit(tileX) {
 				g.markGroundRed(tileX, p.EnemyID, false) // Blood particles never come from platforms
 			}
 		}
@@ -1837,7 +2136,7 @@ func (g *Game) updateBloodParticles(deltaTime float64) {
 		}
 
 		// Remove if off screen or below ground
-		if p.Pos.X < -10 || p.Pos.X > float64(g.width)+10 || p.Pos.Y > float64(g.height) {
+		if p.Pos.X < -10 || p.Pos.X > float64(g.worldWidth)+10 || p.Pos.Y > float64(g.height) {
 			p.Active = false
 		}
 	}
@@ -1898,7 +2197,7 @@ func (g *Game) checkAndClearRedTiles() {
 }
 
 func (g *Game) drawDeathParticles() {
-	now := time.Now()
+	now := g.now
 
 	for i := range g.deathParticles {
 		p := &g.deathParticles[i]
@@ -1910,7 +2209,7 @@ func (g *Game) drawDeathParticles() {
 		y := int(p.Pos.Y)
 
 		// Flash before disappearing (last 0.5 seconds) - but not for player pieces
-		if p.EnemyID != 0 && p.OnGround && time.Since(p.GroundTime) >= 2500*time.Millisecond {
+		if p.EnemyID != 0 && p.OnGround && g.now.Sub(p.GroundTime) >= 2500*time.Millisecond {
 			// Flash every 100ms (only for enemy pieces, not player)
 			if (now.UnixNano()/int64(100*time.Millisecond))%2 == 0 {
 				continue // Skip rendering this frame
@@ -1958,7 +2257,7 @@ func (g *Game) drawDeathParticles() {
 			// Enemy death particles are light gray
 			style = tcell.StyleDefault.Foreground(tcell.ColorLightGray)
 		}
-		g.screen.SetContent(x, y, p.Char, nil, style)
+		g.screen.SetContent(x-g.cameraX, y, p.Char, nil, style)
 	}
 }
 
@@ -1978,7 +2277,7 @@ func (g *Game) drawBloodParticles() {
 		y := int(p.Pos.Y)
 
 		// Only render if on screen
-		if x >= 0 && x < g.width && y >= 0 && y < g.height {
+		if x >= g.cameraX && x < g.cameraX+g.width && y >= 0 && y < g.height {
 			var style tcell.Style
 
 			switch g.bloodColorMode {
@@ -2002,56 +2301,19 @@ func (g *Game) drawBloodParticles() {
 				style = tcell.StyleDefault.Foreground(tcell.ColorRed)
 			}
 
-			g.screen.SetContent(x, y, p.Char, nil, style)
+			g.screen.SetContent(x-g.cameraX, y, p.Char, nil, style)
 		}
 	}
 }
 
 func (g *Game) checkCollisions() {
-	// Check player-enemy collisions
-	for i := range g.enemies {
-		if !g.enemies[i].Active {
-			continue
-		}
-
-		// Simple bounding box collision
-		if g.player.Pos.X < g.enemies[i].Pos.X+float64(g.enemies[i].Width) &&
-			g.player.Pos.X+float64(g.player.Width) > g.enemies[i].Pos.X &&
-			g.player.Pos.Y < g.enemies[i].Pos.Y+float64(g.enemies[i].Height) &&
-			g.player.Pos.Y+float64(g.player.Height) > g.enemies[i].Pos.Y {
-			// Player hit! Create death particles and set game over
-			g.createPlayerDeathParticles()
-			g.gameOver = true
-			return
-		}
-	}
-
-	// Check enemy projectile-player collisions
-	for i := range g.projectiles {
-		if !g.projectiles[i].Active || !g.projectiles[i].IsEnemy {
-			continue
-		}
-
-		projX := g.projectiles[i].Pos.X
-		projY := g.projectiles[i].Pos.Y
-		projW := 1.0
-		projH := 1.0
-
-		// Check collision with player
-		if projX < g.player.Pos.X+float64(g.player.Width) &&
-			projX+projW > g.player.Pos.X &&
-			projY < g.player.Pos.Y+float64(g.player.Height) &&
-			projY+projH > g.player.Pos.Y {
-			// Hit player! Create death particles and set game over
-			g.createPlayerDeathParticles()
-			g.gameOver = true
-			return
-		}
+	for i := range g.players {
+		g.checkPlayerCollisions(&g.players[i])
 	}
 
 	// Check player projectile-enemy collisions (only player projectiles)
 	for i := range g.projectiles {
-		if !g.projectiles[i].Active || g.projectiles[i].IsEnemy {
+		if !g.projectiles[i].Active || g.projectiles[i].Owner == ownerEnemy {
 			continue // Skip enemy projectiles
 		}
 
@@ -2060,128 +2322,183 @@ func (g *Game) checkCollisions() {
 				continue
 			}
 
-			// Swept AABB collision detection to handle fast-moving projectiles
-			// Check if projectile's path (from previous position to current) intersects enemy
-			projPrevX := g.projectiles[i].PrevPos.X
-			projPrevY := g.projectiles[i].PrevPos.Y
-			projCurrX := g.projectiles[i].Pos.X
-			projCurrY := g.projectiles[i].Pos.Y
-			projW := 1.0
-			projH := 1.0
-
-			enemyX := g.enemies[j].Pos.X
-			enemyY := g.enemies[j].Pos.Y
-			enemyW := float64(g.enemies[j].Width)
-			enemyH := float64(g.enemies[j].Height)
-
-			// Check current position
-			hit := false
-			if projCurrX < enemyX+enemyW &&
-				projCurrX+projW > enemyX &&
-				projCurrY < enemyY+enemyH &&
-				projCurrY+projH > enemyY {
-				hit = true
-			}
-
-			// Check previous position (in case we moved through it)
-			if !hit && projPrevX < enemyX+enemyW &&
-				projPrevX+projW > enemyX &&
-				projPrevY < enemyY+enemyH &&
-				projPrevY+projH > enemyY {
-				hit = true
-			}
-
-			// Check intermediate positions along the path (swept collision)
-			if !hit {
-				// Sample points along the path
-				dx := projCurrX - projPrevX
-				dy := projCurrY - projPrevY
-				steps := 5 // Check 5 points along the path
-				for k := 1; k < steps; k++ {
-					t := float64(k) / float64(steps)
-					checkX := projPrevX + dx*t
-					checkY := projPrevY + dy*t
-
-					if checkX < enemyX+enemyW &&
-						checkX+projW > enemyX &&
-						checkY < enemyY+enemyH &&
-						checkY+projH > enemyY {
-						hit = true
-						break
-					}
-				}
-			}
+			hit := projectileHits(&g.projectiles[i], g.enemies[j].Pos, g.enemies[j].Width, g.enemies[j].Height)
 
 			if hit {
 				// Hit! Enemy killed (all enemies have 1 health)
 				g.projectiles[i].Active = false
-				g.createDeathParticles(&g.enemies[j])
-				g.enemies[j].Active = false
-				g.score += 10
-				g.enemiesDefeated++
+				g.killEnemy(&g.enemies[j], g.thrower(&g.projectiles[i]), causeShuriken)
 				break // Projectile can only hit one enemy
 			}
 		}
 	}
 }
 
+// projectileHits reports whether a projectile touched a body this frame. The
+// path from its previous position is swept so fast projectiles can't skip
+// through anything.
+func projectileHits(pr *Projectile, pos Vec2, width, height int) bool {
+	projW := 1.0
+	projH := 1.0
+	w := float64(width)
+	h := float64(height)
+
+	// Check the previous position, the current one and points in between
+	dx := pr.Pos.X - pr.PrevPos.X
+	dy := pr.Pos.Y - pr.PrevPos.Y
+	for k := 0; k <= projectileSweepSteps; k++ {
+		t := float64(k) / projectileSweepSteps
+		checkX := pr.PrevPos.X + dx*t
+		checkY := pr.PrevPos.Y + dy*t
+
+		if checkX < pos.X+w &&
+			checkX+projW > pos.X &&
+			checkY < pos.Y+h &&
+			checkY+projH > pos.Y {
+			return true
+		}
+	}
+	return false
+}
+
+// thrower returns the player who threw a projectile (nil for enemy projectiles)
+func (g *Game) thrower(pr *Projectile) *Player {
+	if pr.Owner < 0 || pr.Owner >= len(g.players) {
+		return nil
+	}
+	return &g.players[pr.Owner]
+}
+
+// hurts reports whether a projectile can hurt a player: enemy projectiles
+// always can, and in versus so can the other player's shuriken
+func (g *Game) hurts(pr *Projectile, p *Player) bool {
+	if pr.Owner == ownerEnemy {
+		return true
+	}
+	return g.match != nil && pr.Owner != p.Index
+}
+
+// checkPlayerCollisions kills a player touching an enemy or a projectile
+func (g *Game) checkPlayerCollisions(p *Player) {
+	if p.Dead {
+		return
+	}
+
+	// Check player-enemy collisions
+	for i := range g.enemies {
+		if !g.enemies[i].Active {
+			continue
+		}
+
+		// Simple bounding box collision
+		if p.Pos.X < g.enemies[i].Pos.X+float64(g.enemies[i].Width) &&
+			p.Pos.X+float64(p.Width) > g.enemies[i].Pos.X &&
+			p.Pos.Y < g.enemies[i].Pos.Y+float64(g.enemies[i].Height) &&
+			p.Pos.Y+float64(p.Height) > g.enemies[i].Pos.Y {
+			// Player hit! Create death particles and lose a life
+			g.killPlayer(p, causeTouch, nil)
+			return
+		}
+	}
+
+	// Check projectile-player collisions
+	for i := range g.projectiles {
+		if !g.projectiles[i].Active || !g.hurts(&g.projectiles[i], p) {
+			continue
+		}
+
+		if projectileHits(&g.projectiles[i], p.Pos, p.Width, p.Height) {
+			// Hit player! Create death particles and lose a life
+			if !g.now.Before(p.SafeUntil) {
+				g.projectiles[i].Active = false
+			}
+			g.killPlayer(p, causeShuriken, g.thrower(&g.projectiles[i]))
+			return
+		}
+	}
+}
+
+// killEnemy blows an enemy apart, crediting the player who got it (nil if
+// nobody gets the points). The cause is causeShuriken or a hazard kind.
+func (g *Game) killEnemy(e *Enemy, by *Player, cause string) {
+	decapitated := g.createDeathParticles(e)
+	e.Active = false
+	g.enemiesDefeated++
+	g.events.publish(EnemyKilled{EnemyID: e.ID, Kind: e.Kind, Pos: e.Pos, Cause: cause, By: playerIndex(by)})
+	if decapitated {
+		g.events.publish(Decapitation{EnemyID: e.ID, Pos: e.Pos, By: playerIndex(by)})
+	}
+}
+
 func (g *Game) handleInput(ev *tcell.EventKey) {
+	if g.editor != nil && g.editor.Playtesting {
+		// ESC or T returns from a playtest, as does ENTER once it's over
+		if ev.Key() == tcell.KeyEscape || (ev.Key() == tcell.KeyRune && (ev.Rune() == 't' || ev.Rune() == 'T')) ||
+			(g.gameOver && ev.Key() == tcell.KeyEnter) {
+			g.stopPlaytest()
+			return
+		}
+	}
+
 	if g.inMenu {
+		switch g.menuScreen {
+		case screenLevels:
+			g.handleLevelSelectInput(ev)
+			return
+		case screenEditor:
+			g.handleEditorInput(ev)
+			return
+		case screenStats:
+			g.handleStatsInput(ev)
+			return
+		case screenAchievements:
+			g.handleAchievementsInput(ev)
+			return
+		}
+
 		switch ev.Key() {
 		case tcell.KeyRune:
-			if ev.Rune() == ' ' {
-				// Start game - reset everything
-				// Clear all menu demo entities
-				g.projectiles = make([]Projectile, 0)
-				g.enemies = make([]Enemy, 0)
-				g.deathParticles = make([]DeathParticle, 0)
-				g.bloodParticles = make([]BloodParticle, 0)
-				g.redGroundTiles = make(map[int]int)
-				g.redPlatformTiles = make(map[int]int)
-
-				// Reset player to starting position
-				g.player = Player{
-					Pos:              Vec2{X: float64(g.width / 2), Y: float64(g.groundY - PlayerHeight)},
-					Vel:              Vec2{X: 0, Y: 0},
-					Facing:           1,
-					MoveDir:          0,
-					Width:            PlayerWidth,
-					Height:           PlayerHeight,
-					OnGround:         true,
-					OnPlatform:       false,
-					LastOnGroundTime: time.Now(),
-				}
-
-				// Reset game state
-				g.score = 0
-				g.enemiesDefeated = 0
-				g.enemySpawnCounter = 0
-				g.gameOver = false
-				g.nextEnemyID = 1
-				g.lastShot = time.Time{}
-				g.keys = make(map[tcell.Key]time.Time)
-
-				// Recreate platforms for the new game
-				platforms := make([]Platform, 0)
-				numPlatforms := 3 + rand.Intn(3) // 3-5 platforms
-				platformSpacing := float64(g.width) / float64(numPlatforms+1)
-				groundLevel := float64(g.groundY)
-				for i := 0; i < numPlatforms; i++ {
-					platformX := platformSpacing * float64(i+1)
-					heightAboveGround := 4.0 + rand.Float64()*8.0      // 4-12 pixels above ground
-					platformY := groundLevel - heightAboveGround - 1.0 // -1 to account for platform height
-					platformWidth := 12.0 + rand.Float64()*16.0        // 12-28 wide - wider platforms
-					platforms = append(platforms, Platform{
-						X:      platformX - platformWidth/2,
-						Y:      platformY,
-						Width:  platformWidth,
-						Height: 1.0,
-					})
+			switch ev.Rune() {
+			case ' ', '2':
+				// Space starts a solo run, 2 a co-op run
+				g.menuNote = ""
+				g.numPlayers = 1
+				if ev.Rune() == '2' {
+					g.numPlayers = g.multiplayerCount()
 				}
-				g.platforms = platforms
 
-				// Start game
+				// Recreate the level for the new game and reset everything
 				g.inMenu = false
+				g.buildLevel()
+				g.resetRun()
+			case 'v', 'V':
+				g.startVersus()
+			case 'r', 'R':
+				// Cycle the rounds needed to win a versus match
+				g.versusRounds = g.versusRounds%9 + 1
+			case 'n', 'N':
+				g.versusEnemies = !g.versusEnemies
+			case 'g', 'G':
+				g.ghostsOn = !g.ghostsOn && g.ghostDir != ""
+			case 'd', 'D':
+				g.setDifficulty(nextDifficulty(g.difficulty))
+			case 's', 'S':
+				g.menuScreen = screenStats
+			case 'a', 'A':
+				g.menuScreen = screenAchievements
+			case 'c', 'C':
+				if g.hasSave {
+					if err := g.loadRun(); err != nil {
+						g.menuNote = err.Error()
+						g.hasSave = false
+					}
+				}
+			case 'l', 'L':
+				// Open level select
+				g.levelCursor = g.levelIndex
+				g.menuScreen = screenLevels
+			case 'e', 'E':
+				g.openEditor()
 			}
 		case tcell.KeyTab:
 			// Toggle blood color mode
@@ -2196,251 +2513,136 @@ func (g *Game) handleInput(ev *tcell.EventKey) {
 		switch ev.Key() {
 		case tcell.KeyEnter:
 			// Restart game - go back to menu
-			// Recreate platforms on restart
-			platforms := make([]Platform, 0)
-			numPlatforms := 3 + rand.Intn(3) // 3-5 platforms
-			platformSpacing := float64(g.width) / float64(numPlatforms+1)
-			groundLevel := float64(g.groundY)
-			for i := 0; i < numPlatforms; i++ {
-				platformX := platformSpacing * float64(i+1)
-				// Platforms at different heights: 4-12 pixels above ground (reachable with jump)
-				heightAboveGround := 4.0 + rand.Float64()*8.0      // 4-12 pixels above ground
-				platformY := groundLevel - heightAboveGround - 1.0 // -1 to account for platform height
-				platformWidth := 12.0 + rand.Float64()*16.0        // 12-28 wide - wider platforms
-				platforms = append(platforms, Platform{
-					X:      platformX - platformWidth/2,
-					Y:      platformY,
-					Width:  platformWidth,
-					Height: 1.0,
-				})
-			}
-
-			g.player = Player{
-				Pos:              Vec2{X: float64(g.width / 2), Y: float64(g.groundY - PlayerHeight)},
-				Vel:              Vec2{X: 0, Y: 0},
-				Facing:           1,
-				MoveDir:          0,
-				Width:            PlayerWidth,
-				Height:           PlayerHeight,
-				OnGround:         true,
-				OnPlatform:       false,
-				LastOnGroundTime: time.Now(),
+			// Recreate the level on restart
+			g.numPlayers = 1 // The menu demo has a single ninja
+			g.match = nil
+			if g.pickedDifficulty.Name != "" {
+				g.difficulty, g.pickedDifficulty = g.pickedDifficulty, Difficulty{}
 			}
+			g.buildLevel()
+			g.resetPlayers()
 			g.projectiles = make([]Projectile, 0)
 			g.enemies = make([]Enemy, 0)
-			g.platforms = platforms
 			// Clear all particles on restart
 			g.deathParticles = make([]DeathParticle, 0)
 			g.bloodParticles = make([]BloodParticle, 0)
 			g.redGroundTiles = make(map[int]int)
 			g.redPlatformTiles = make(map[int]int)
-			g.score = 0
 			g.enemiesDefeated = 0
 			g.enemySpawnCounter = 0
 			g.gameOver = false
 			g.inMenu = true
-			g.keys = make(map[tcell.Key]time.Time)
+			g.demo = nil // Back in the menu, a new demo starts
 		case tcell.KeyEscape:
 			// Exit handled by main loop
 		}
 		return
 	}
 
+	if ev.Key() == tcell.KeyF3 {
+		g.debugOn = !g.debugOn
+		return
+	}
+
 	// Handle TAB to toggle blood color even during gameplay
 	if ev.Key() == tcell.KeyTab {
 		g.bloodColorMode = (g.bloodColorMode + 1) % 4
 		return
 	}
 
-	// Track key states for smooth movement
-	switch ev.Key() {
-	case tcell.KeyLeft:
-		g.keys[tcell.KeyLeft] = time.Now()
-	case tcell.KeyRight:
-		g.keys[tcell.KeyRight] = time.Now()
-	case tcell.KeyUp:
-		g.keys[tcell.KeyUp] = time.Now()
-	case tcell.KeyRune:
-		if ev.Rune() == ' ' {
-			// Fire projectile (with cooldown to prevent spam)
-			now := time.Now()
-			if now.Sub(g.lastShot) > 200*time.Millisecond {
-				p := Projectile{
-					Pos:     Vec2{X: g.player.Pos.X + float64(g.player.Width/2), Y: g.player.Pos.Y + float64(g.player.Height/2)},
-					PrevPos: Vec2{X: g.player.Pos.X + float64(g.player.Width/2), Y: g.player.Pos.Y + float64(g.player.Height/2)},
-					Dir:     g.player.Facing,
-					Active:  true,
-					Frame:   0,
-					IsEnemy: false,
-				}
-				g.projectiles = append(g.projectiles, p)
-				g.lastShot = now
-			}
-		}
-	}
+	// Movement and throwing keys, for whichever player they belong to
+	g.handlePlayerKey(ev)
 }
 
+// updateMenu plays the attract-mode demo behind the menu: an AI ninja plays
+// a solo run on the selected level, and another starts a few seconds after
+// it ends
 func (g *Game) updateMenu(deltaTime float64) {
-	// Update menu demo - player fires projectiles and enemies spawn
-	now := time.Now()
-
-	// Position menu player in center of screen
-	if g.player.Pos.X == 0 && g.player.Pos.Y == 0 {
-		g.player.Pos.X = float64(g.width / 2)
-		g.player.Pos.Y = float64(g.groundY - PlayerHeight)
-		g.player.Facing = 1
-	}
-
-	// Menu player fires projectiles periodically (every 0.8 seconds)
-	if now.Sub(g.menuLastShot) > 800*time.Millisecond {
-		// Fire in random direction
-		dir := 1
-		if rand.Float64() < 0.5 {
-			dir = -1
-		}
-		g.player.Facing = dir
-
-		p := Projectile{
-			Pos:     Vec2{X: g.player.Pos.X + float64(g.player.Width/2), Y: g.player.Pos.Y + float64(g.player.Height/2)},
-			PrevPos: Vec2{X: g.player.Pos.X + float64(g.player.Width/2), Y: g.player.Pos.Y + float64(g.player.Height/2)},
-			Dir:     dir,
-			Active:  true,
-			Frame:   0,
-			IsEnemy: false,
-		}
-		g.projectiles = append(g.projectiles, p)
-		g.menuLastShot = now
-	}
-
-	// Spawn enemies occasionally (every 1.5-3 seconds)
-	if now.Sub(g.menuLastEnemySpawn) > time.Duration(1500+rand.Intn(1500))*time.Millisecond {
-		var e Enemy
-		canShoot := false // Menu enemies don't shoot
-
-		if rand.Float64() < 0.5 {
-			// Spawn from left
-			e = Enemy{
-				Pos:           Vec2{X: -float64(EnemyWidth), Y: float64(g.groundY - EnemyHeight)},
-				Vel:           Vec2{X: 0, Y: 0},
-				Facing:        1,
-				Width:         EnemyWidth,
-				Height:        EnemyHeight,
-				Active:        true,
-				CanShoot:      canShoot,
-				LastShot:      time.Time{},
-				NextShotDelay: 0,
-				OnGround:      true,
-				JumpCooldown:  time.Time{},
-			}
-		} else {
-			// Spawn from right
-			e = Enemy{
-				Pos:           Vec2{X: float64(g.width), Y: float64(g.groundY - EnemyHeight)},
-				Vel:           Vec2{X: 0, Y: 0},
-				Facing:        -1,
-				Width:         EnemyWidth,
-				Height:        EnemyHeight,
-				Active:        true,
-				CanShoot:      canShoot,
-				LastShot:      time.Time{},
-				NextShotDelay: 0,
-				OnGround:      true,
-				JumpCooldown:  time.Time{},
-			}
-		}
-		g.enemies = append(g.enemies, e)
-		g.menuLastEnemySpawn = now
-	}
-
-	// Update menu enemies (move towards center, no jumping)
-	baseSpeed := 20.0
-	for i := range g.enemies {
-		if !g.enemies[i].Active {
-			continue
-		}
-
-		// Move towards center of screen
-		centerX := float64(g.width / 2)
-		dx := centerX - g.enemies[i].Pos.X
-		if dx > 0 {
-			g.enemies[i].Facing = 1
-			g.enemies[i].Pos.X += baseSpeed * deltaTime
-		} else {
-			g.enemies[i].Facing = -1
-			g.enemies[i].Pos.X -= baseSpeed * deltaTime
-		}
-
-		// Remove enemies that go off screen or reach center
-		if g.enemies[i].Pos.X < -float64(EnemyWidth) ||
-			g.enemies[i].Pos.X > float64(g.width) ||
-			math.Abs(g.enemies[i].Pos.X-centerX) < 5.0 {
-			g.enemies[i].Active = false
-		}
+	if g.demo == nil || (g.gameOver && g.now.Sub(g.demoEnded) > demoRestartDelay) {
+		g.startDemo()
 	}
-
-	// Clean up inactive enemies
-	active := g.enemies[:0]
-	for i := range g.enemies {
-		if g.enemies[i].Active {
-			active = append(active, g.enemies[i])
-		}
-	}
-	g.enemies = active
-
-	// Update projectiles
-	g.updateProjectiles(deltaTime)
-
-	// Check collisions for menu demo (enemies hit by projectiles)
-	for i := range g.projectiles {
-		if !g.projectiles[i].Active || g.projectiles[i].IsEnemy {
-			continue
-		}
-
-		for j := range g.enemies {
-			if !g.enemies[j].Active {
-				continue
-			}
-
-			projX := g.projectiles[i].Pos.X
-			projY := g.projectiles[i].Pos.Y
-			enemyX := g.enemies[j].Pos.X
-			enemyY := g.enemies[j].Pos.Y
-
-			if projX < enemyX+float64(g.enemies[j].Width) &&
-				projX+1.0 > enemyX &&
-				projY < enemyY+float64(g.enemies[j].Height) &&
-				projY+1.0 > enemyY {
-				// Hit! Create death particles and remove enemy
-				g.createDeathParticles(&g.enemies[j])
-				g.enemies[j].Active = false
-				g.projectiles[i].Active = false
-				break
-			}
+	if !g.gameOver {
+		g.demo.act(g, &g.players[0])
+		g.updateRun(deltaTime)
+		if g.gameOver {
+			g.demoEnded = g.now
 		}
 	}
+	g.updateWorld(deltaTime)
+}
 
-	// Update death particles
-	g.updateDeathParticles(deltaTime)
-	g.updateBloodParticles(deltaTime)
-	g.checkAndClearRedTiles()
+// startDemo starts a new attract-mode run
+func (g *Game) startDemo() {
+	g.numPlayers = 1
+	g.match = nil
+	g.resetRun()
+	g.demo = newAIPlayer(rand.Int63())
 }
 
 func (g *Game) update(deltaTime float64) {
-	// Update menu demo if in menu
+	g.now = g.now.Add(time.Duration(deltaTime * float64(time.Second)))
+
+	// Update menu demo if in menu (the editor and the level select opened
+	// from it stay still, so the layout being edited isn't played on)
 	if g.inMenu {
-		g.updateMenu(deltaTime)
+		if g.editor == nil {
+			g.updateMenu(deltaTime)
+		}
 		return
 	}
 
 	if !g.gameOver {
-		// Only update player when game is active
-		g.updatePlayer(deltaTime)
-		g.checkCollisions()
+		g.updateRun(deltaTime)
 	}
 
 	// Always update projectiles, enemies, and particles (even when game over)
+	g.updateWorld(deltaTime)
+}
+
+// updateRun moves the players and checks what happened to them, ending the
+// run when it's over
+func (g *Game) updateRun(deltaTime float64) {
+	held := g.players[0].heldActions(g.now)
+	for i := range g.players {
+		g.updatePlayer(&g.players[i], deltaTime)
+	}
+	g.recordFrame(deltaTime, held)
+	g.updateGhost(deltaTime)
+	g.updateRespawns()
+	g.updateCamera()
+	g.checkCollisions()
+	g.checkPlayerHazards()
+
+	if g.match != nil {
+		g.updateMatch()
+	} else if !g.gameOver && g.reachedGoal() {
+		// Stage levels end when the player reaches the goal
+		g.stageClear = true
+		g.gameOver = true
+	}
+	g.checkAchievements()
+	if g.telemetry != nil {
+		g.telemetry.update()
+	}
+	if g.gameOver {
+		g.runTime = g.now.Sub(g.runStart)
+		g.recordScores()
+		g.recordStats()
+		g.finishRecording()
+		scores := make([]int, len(g.players))
+		for i := range g.players {
+			scores[i] = g.players[i].Score
+		}
+		g.events.publish(RunEnded{Scores: scores, Time: g.runTime.Seconds(), Clear: g.stageClear})
+	}
+}
+
+// updateWorld moves everything but the players: shuriken, enemies and
+// particles
+func (g *Game) updateWorld(deltaTime float64) {
 	g.updateProjectiles(deltaTime)
 	g.updateEnemies(deltaTime)
+	g.checkEnemyHazards()
 	g.updateDeathParticles(deltaTime)
 	g.updateBloodParticles(deltaTime)
 	g.checkAndClearRedTiles() // Clear red tiles for enemies that are gone
@@ -2452,8 +2654,10 @@ func (g *Game) render() {
 	if g.inMenu {
 		// Draw game elements for menu demo
 		g.drawGround()
+		g.drawHazards()
 		g.drawPlatforms()
-		g.drawPlayer()
+		g.drawGoal()
+		g.drawPlayer(&g.players[0])
 
 		for i := range g.projectiles {
 			if g.projectiles[i].Active {
@@ -2478,15 +2682,34 @@ func (g *Game) render() {
 		g.drawBloodParticles()
 
 		// Draw menu text on top
-		g.drawMenu()
+		switch g.menuScreen {
+		case screenLevels:
+			g.drawLevelSelect()
+		case screenEditor:
+			g.drawEditor()
+		case screenStats:
+			g.drawStats()
+		case screenAchievements:
+			g.drawAchievementGallery()
+		default:
+			g.drawMenu()
+		}
 	} else {
 		g.drawGround()
+		g.drawHazards()
 		g.drawPlatforms()
+		g.drawGoal()
 		g.drawScore()
+		if g.match != nil {
+			g.drawRoundOver()
+		}
+		g.drawGhost()
 
-		if !g.gameOver {
-			g.drawPlayer()
-		} else {
+		// Downed players aren't drawn
+		for i := range g.players {
+			g.drawPlayer(&g.players[i])
+		}
+		if g.gameOver {
 			g.drawGameOver()
 		}
 
@@ -2511,18 +2734,70 @@ func (g *Game) render() {
 
 		g.drawDeathParticles()
 		g.drawBloodParticles()
+		if g.watching != "" {
+			g.drawWatching()
+		}
+		g.drawToasts()
+	}
+	if g.debugOn {
+		g.drawDebug()
 	}
 
 	g.screen.Show()
 }
 
-func (g *Game) run() {
+// resize fits the game to a new screen size. The level and everything in it
+// move with the ground, as buildLevel would place them.
+func (g *Game) resize(width, height int) {
+	ghostPath := g.ghostPath()
+	g.width, g.height = width, height
+	dy := g.height - 1 - g.groundY
+	g.groundY = g.height - 1
+	g.shiftWorld(float64(dy))
+
+	// The world is never narrower than the screen
+	g.worldWidth = g.width
+	if g.level.Width > g.worldWidth {
+		g.worldWidth = g.level.Width
+	}
+	if ed := g.editor; ed != nil {
+		ed.CursorX = min(ed.CursorX, g.worldWidth-1)
+		ed.CursorY = min(max(ed.CursorY+dy, 0), g.groundY)
+	}
+	for i := range g.players {
+		p := &g.players[i]
+		// Keep player in bounds after resize
+		if p.Pos.X+float64(p.Width) > float64(g.worldWidth) {
+			p.Pos.X = float64(g.worldWidth - p.Width)
+		}
+	}
+	g.clampCamera()
+
+	// Ghosts are kept per layout size, so the run no longer races one or
+	// counts as one
+	if g.ghostPath() != ghostPath {
+		g.recording, g.ghost = nil, nil
+	}
+}
+
+// run plays until the player quits. Quitting in the middle of a run saves it;
+// the error is from saving.
+func (g *Game) run() error {
 	// Start input handling goroutine
 	inputChan := make(chan tcell.Event, 10)
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
 		for {
 			ev := g.screen.PollEvent()
-			inputChan <- ev
+			if ev == nil {
+				return // Screen finalized
+			}
+			select {
+			case inputChan <- ev:
+			case <-done:
+				return
+			}
 		}
 	}()
 
@@ -2532,7 +2807,8 @@ func (g *Game) run() {
 	for {
 		// Handle timing
 		now := time.Now()
-		deltaTime := now.Sub(g.lastFrame).Seconds()
+		g.frameTime = now.Sub(g.lastFrame)
+		deltaTime := g.frameTime.Seconds()
 		g.lastFrame = now
 
 		// Cap delta time to prevent large jumps
@@ -2545,19 +2821,18 @@ func (g *Game) run() {
 		case ev := <-inputChan:
 			switch ev := ev.(type) {
 			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyEscape {
-					return
+				// ESC quits, except inside a menu page or the editor where it goes back
+				if ev.Key() == tcell.KeyEscape && g.editor == nil && !(g.inMenu && g.menuScreen != screenMain) {
+					if g.canSave() {
+						return g.saveRun()
+					}
+					return nil
 				}
 				g.handleInput(ev)
 			case *tcell.EventResize:
-				g.width, g.height = g.screen.Size()
-				g.groundY = g.height - 1 // Update ground position
-				// Keep player in bounds after resize
-				if g.player.Pos.X+float64(g.player.Width) > float64(g.width) {
-					g.player.Pos.X = float64(g.width - g.player.Width)
-				}
-				// Update player Y position to stay on ground
-				g.player.Pos.Y = float64(g.groundY - PlayerHeight)
+				g.resize(g.screen.Size())
+			case *tcell.EventError:
+				return nil // The terminal went away (an SSH client disconnected)
 			}
 		default:
 			// No input available, continue
@@ -2567,11 +2842,18 @@ func (g *Game) run() {
 		// In a real implementation, we'd track key releases, but for simplicity
 		// we'll let keys stay pressed until another key is pressed
 
+		// Pick up changes designers make to the tuning
+		g.watchTuning()
+
+		// Apply what network players pressed
+		g.pollNetwork()
+
 		// Update game state
 		g.update(deltaTime)
 
 		// Render
 		g.render()
+		g.broadcastState()
 
 		// Wait for next frame
 		<-ticker.C
@@ -2579,16 +2861,75 @@ func (g *Game) run() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serveSSH(os.Args[2:])
+		return
+	}
+
+	levelPath := flag.String("level", "", "path to a level file to play")
+	seed := flag.Int64("seed", 0, "seed for generated levels (0 = random)")
+	hostAddr := flag.String("host", "", "host a network game, listening on this address (e.g. :7777)")
+	joinAddr := flag.String("join", "", "join a network game at this address (e.g. localhost:7777)")
+	watchAddr := flag.String("watch", "", "watch a network game at this address without playing")
+	telemetryPath := flag.String("telemetry", "", "log every spawn, shot, hit, kill and death to this file as JSON lines")
+	agent := flag.Bool("agent", false, "run without a terminal for a bot: JSON actions on stdin, observations on stdout")
+	soak := flag.Int("soak", 0, "have the AI play this many games headless and report how they went")
+	tuningFile := flag.String("tuning", "", "read physics and particle tuning from this JSON file (default ~/.config/gninja/tuning.json when playing)")
+	difficulty := flag.String("difficulty", "", "play at this difficulty (easy, normal, hard or nightmare) instead of the one picked on the title screen")
+	flag.Parse()
+
+	if *agent && (*hostAddr != "" || *joinAddr != "" || *watchAddr != "") {
+		fmt.Fprintln(os.Stderr, "--agent can't be combined with network play")
+		os.Exit(2)
+	}
+	if *soak > 0 && (*agent || *hostAddr != "" || *joinAddr != "" || *watchAddr != "") {
+		fmt.Fprintln(os.Stderr, "--soak can't be combined with --agent or network play")
+		os.Exit(2)
+	}
+
 	// Initialize random seed
 	rand.Seed(time.Now().UnixNano())
 
-	// Initialize screen
-	screen, err := tcell.NewScreen()
-	if err != nil {
-		panic(err)
+	// Load the level up front so a bad file is reported before the screen takes over
+	if *levelPath != "" {
+		if _, err := LoadLevel(*levelPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	// Connect before the screen takes over so errors are readable
+	var host *netHost
+	var client *netClient
+	if *hostAddr != "" {
+		var err error
+		if host, err = startHost(*hostAddr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *joinAddr != "" || *watchAddr != "" {
+		var err error
+		if *watchAddr != "" {
+			client, err = watchHost(*watchAddr)
+		} else {
+			client, err = joinHost(*joinAddr)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 	}
 
-	if err := screen.Init(); err != nil {
+	// Initialize screen (one nobody sees for agents and soak tests)
+	var screen tcell.Screen
+	var err error
+	if *agent || *soak > 0 {
+		screen, err = newAgentScreen()
+	} else if screen, err = tcell.NewScreen(); err == nil {
+		err = screen.Init()
+	}
+	if err != nil {
 		panic(err)
 	}
 	defer screen.Fini()
@@ -2600,5 +2941,92 @@ func main() {
 
 	// Create and run game
 	game := NewGame(screen)
-	game.run()
+	if client != nil {
+		if err := game.runClient(client); err != nil {
+			screen.Fini()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	game.host = host
+	game.playerName = localPlayerName()
+	if !*agent && *soak == 0 {
+		game.savePath = defaultSavePath()
+		if _, err := os.Stat(game.savePath); err == nil {
+			game.hasSave = true
+		}
+		game.scores = loadHighScores(highScoresPath())
+		game.stats = loadStatsBook(statsPath())
+		game.achievements = loadAchievementBook(achievementsPath())
+		game.settings = loadSettingsBook(settingsPath())
+		game.ghostDir = ghostDir()
+		game.loadSettings()
+		game.tuningPath = tuningPath()
+	}
+	if *tuningFile != "" {
+		t, err := loadTuning(*tuningFile)
+		if err == nil {
+			_, err = os.Stat(*tuningFile)
+		}
+		if err != nil {
+			screen.Fini()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		game.tuning = t
+		game.tuningPath = *tuningFile
+	}
+	game.watchTuning()
+	if *difficulty != "" {
+		d, ok := difficultyNamed(*difficulty)
+		if !ok {
+			screen.Fini()
+			fmt.Fprintf(os.Stderr, "no difficulty called %q\n", *difficulty)
+			os.Exit(1)
+		}
+		game.difficulty = d
+	}
+	if *telemetryPath != "" {
+		if _, err := startTelemetry(game, *telemetryPath); err != nil {
+			screen.Fini()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *seed != 0 {
+		game.levelSeed = *seed
+		game.buildLevel()
+		game.resetPlayers()
+	}
+	if *levelPath != "" {
+		if err := game.addLevelFile(*levelPath); err != nil {
+			screen.Fini()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *soak > 0 {
+		seed := *seed
+		if seed == 0 {
+			seed = 1
+		}
+		if !game.runSoak(*soak, seed, os.Stdout) {
+			err = fmt.Errorf("soak test failed")
+		}
+	} else if *agent {
+		err = game.runAgent(os.Stdin, os.Stdout)
+	} else {
+		err = game.run()
+	}
+	if game.telemetry != nil {
+		if terr := game.telemetry.close(); err == nil && terr != nil {
+			err = fmt.Errorf("writing telemetry: %w", terr)
+		}
+	}
+	if err != nil {
+		screen.Fini()
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
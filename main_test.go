@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// newTestGame is a game on a 100x30 simulated screen, saving nothing outside
+// the test's own directories
+func newTestGame(t *testing.T) *Game {
+	t.Helper()
+	return newTestGameSized(t, 100, 30)
+}
+
+// newTestGameSized is newTestGame on a screen of another size
+func newTestGameSized(t *testing.T, width, height int) *Game {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	s.SetSize(width, height)
+	return NewGame(s)
+}
+
+// pressKey sends the game a key as if it was typed
+func pressKey(g *Game, k tcell.Key, r rune) {
+	g.handleInput(tcell.NewEventKey(k, r, 0))
+}
+
+func TestResizeMovesLevelWithGround(t *testing.T) {
+	g := newTestGame(t)
+	g.ghostDir = t.TempDir()
+	g.levelSeed = 7
+	pressKey(g, tcell.KeyRune, ' ')
+	for f := 0; f < 400; f++ {
+		playFrame(g, f)
+	}
+	if g.recording == nil {
+		t.Fatal("run isn't being recorded")
+	}
+	g.hazards = append(g.hazards, Hazard{Kind: HazardSpikes, X: 10, Y: float64(g.groundY) - 1, Width: 4})
+
+	// Everything is measured up from the ground
+	heights := func() []float64 {
+		var hs []float64
+		ground := float64(g.groundY)
+		for _, p := range g.platforms {
+			hs = append(hs, ground-p.Y)
+		}
+		for _, h := range g.hazards {
+			hs = append(hs, ground-h.Y)
+		}
+		for _, s := range g.enemySpawns {
+			hs = append(hs, ground-s.Y)
+		}
+		for _, e := range g.enemies {
+			hs = append(hs, ground-e.Pos.Y)
+		}
+		return append(hs, ground-g.players[0].Pos.Y)
+	}
+	before := heights()
+	if len(g.enemies) == 0 {
+		t.Fatal("no enemies to move")
+	}
+
+	g.resize(100, 24)
+	if g.groundY != 23 {
+		t.Fatalf("ground at row %d, want 23", g.groundY)
+	}
+	after := heights()
+	for i := range before {
+		if after[i] != before[i] {
+			t.Errorf("thing %d is %v above the ground, was %v", i, after[i], before[i])
+		}
+	}
+	if g.recording != nil || g.ghost != nil {
+		t.Error("run still recorded as a ghost for the old screen size")
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Enemies find their way around a level on a navigation graph. Its nodes are
+// the surfaces an enemy can safely stand on and its edges are the moves
+// between them: walking, jumping and dropping, each checked by following the
+// enemy frame by frame the way the enemy update moves it. The shortest route
+// between every pair of surfaces is worked out up front, so an enemy only has
+// to look up its next move each frame.
+
+// Kinds of navigation edge
+const (
+	navWalk = iota // Step across to a touching surface at the same height
+	navJump        // Jump up or across
+	navDrop        // Walk off an end and fall
+)
+
+const (
+	navMoveCost = 5.0  // Extra cost of a jump or drop over walking the same distance
+	navLevel    = 0.5  // Rows within which feet count as standing on a surface
+	navSlack    = 1.0  // Cells of reach a jump must have to spare
+	navHair     = 0.01 // Overlap too slight to be sure of either way
+)
+
+// navEdge is a move from one surface to another
+type navEdge struct {
+	To   int
+	Kind int
+	X    float64 // Where to take off from for a jump, or walk to for a drop
+	Cost float64
+}
+
+// navGraph is where enemies can get to on the current layout
+type navGraph struct {
+	surfaces []Platform
+	landings []Platform  // The part of each surface a body can come down on without touching a hazard
+	flames   []Platform  // Where fire vents burn, which a jump mustn't pass through
+	edges    [][]navEdge // Moves from each surface
+	next     [][]int     // next[a][b] is the edge of a to take towards b (-1 if b can't be reached)
+	model    jumpModel   // How the enemies move
+}
+
+// navGraph returns the graph for the current layout, building it if the
+// layout has changed since it was last needed
+func (g *Game) navGraph() *navGraph {
+	if g.nav == nil {
+		surfaces, landings := g.navSurfaces()
+		var flames []Platform
+		for _, h := range g.hazards {
+			if h.Kind == HazardFire {
+				flames = append(flames, Platform{X: h.X, Y: h.Y - fireHeight, Width: h.Width, Height: fireHeight + 1})
+			}
+		}
+		g.nav = buildNavGraph(surfaces, landings, flames, float64(g.worldWidth), g.enemyModel())
+	}
+	return g.nav
+}
+
+// navSurfaces is where an enemy can safely stand: the platforms and the
+// ground, less any spikes and fire vents on them and the pits in the ground.
+// A body only falls into a pit when it's entirely over it, which is how
+// platforms work too, so each stretch of ground is a surface like any
+// platform. The ground reaches past the edges of the world, where enemies
+// come in.
+//
+// Spikes and fire hurt a body that so much as overlaps them, and a body that
+// comes down with its middle over a pit falls in, so each surface also has a
+// landing: the surface less the part near an end that runs up to one.
+func (g *Game) navSurfaces() (surfaces, landings []Platform) {
+	ground := Platform{X: -float64(EnemyWidth), Y: float64(g.groundY), Width: float64(g.worldWidth + 2*EnemyWidth), Height: 1}
+	margin := func(h Hazard) float64 {
+		if h.Kind == HazardPit {
+			return float64(EnemyWidth) / 2
+		}
+		return float64(EnemyWidth)
+	}
+	for _, s := range append(append([]Platform{}, g.platforms...), ground) {
+		var gaps []Hazard
+		for _, h := range g.hazards {
+			onIt := h.Y+1 == s.Y && (h.Kind == HazardSpikes || h.Kind == HazardFire)
+			if onIt || (h.Kind == HazardPit && s == ground) {
+				gaps = append(gaps, h)
+			}
+		}
+		sort.Slice(gaps, func(i, j int) bool { return gaps[i].X < gaps[j].X })
+
+		add := func(x, end, left, right float64) {
+			surfaces = append(surfaces, Platform{X: x, Y: s.Y, Width: end - x, Height: s.Height})
+			landings = append(landings, Platform{X: x + left, Y: s.Y, Width: end - x - left - right, Height: s.Height})
+		}
+		x, end := s.X, s.X+s.Width
+		left, right := 0.0, 0.0 // Margins at x for the hazard before it, and at the far end
+		if s == ground {
+			left, right = float64(EnemyWidth), float64(EnemyWidth) // Nothing should end up outside the world
+		}
+		for _, h := range gaps {
+			if h.X > x {
+				add(x, math.Min(h.X, end), left, margin(h))
+			}
+			if h.X+h.Width >= x {
+				left = margin(h)
+			}
+			x = math.Max(x, h.X+h.Width)
+		}
+		if end > x {
+			add(x, end, left, right)
+		}
+	}
+	return surfaces, landings
+}
+
+// buildNavGraph works out the moves between surfaces for a body that moves
+// like m in a world width columns wide, and the shortest routes between
+// every pair
+func buildNavGraph(surfaces, landings, flames []Platform, width float64, m jumpModel) *navGraph {
+	n := &navGraph{surfaces: surfaces, landings: landings, flames: flames, model: m}
+	count := len(n.surfaces)
+	n.edges = make([][]navEdge, count)
+	for a, from := range n.surfaces {
+		for b, to := range n.surfaces {
+			if a == b {
+				continue
+			}
+			gap := m.horizontalGap(from, to)
+			touching := to.X <= from.X+from.Width && from.X <= to.X+to.Width
+			if math.Abs(to.Y-from.Y) < navLevel && touching {
+				n.edges[a] = append(n.edges[a], navEdge{To: b, Kind: navWalk, Cost: gap})
+				continue
+			}
+			// Only up or across: going down is a drop, since jumping to
+			// somewhere below can come down on from again
+			if to.Y >= from.Y+navLevel || n.landings[b].Width <= 0 || !m.canJump(from, n.landings[b], navSlack) {
+				continue
+			}
+			// The arc can reach, but another platform may be in the way, so
+			// find somewhere to take off from that really comes down on to
+			if x, ok := n.takeoff(m, a, b); ok {
+				cost := gap + math.Abs(to.Y-from.Y) + navMoveCost
+				n.edges[a] = append(n.edges[a], navEdge{To: b, Kind: navJump, X: x, Cost: cost})
+			}
+		}
+
+		// Walking off either end lands on whatever is below
+		for _, x := range []float64{from.X - float64(m.Width) - 1, from.X + from.Width + 1} {
+			if x < 0 || x > width-float64(m.Width) {
+				continue // Off the edge of the world
+			}
+			if b, at := n.fall(m, x, from.Y, 0, x); b >= 0 && n.safe(b, at, m.Width) {
+				cost := math.Abs(n.surfaces[b].Y-from.Y) + navMoveCost
+				n.edges[a] = append(n.edges[a], navEdge{To: b, Kind: navDrop, X: x, Cost: cost})
+			}
+		}
+	}
+
+	// Floyd-Warshall, remembering the first edge of each route
+	dist := make([][]float64, count)
+	n.next = make([][]int, count)
+	for a := range dist {
+		dist[a] = make([]float64, count)
+		n.next[a] = make([]int, count)
+		for b := range dist[a] {
+			dist[a][b] = math.Inf(1)
+			n.next[a][b] = -1
+		}
+		dist[a][a] = 0
+		for i, e := range n.edges[a] {
+			if e.Cost < dist[a][e.To] {
+				dist[a][e.To] = e.Cost
+				n.next[a][e.To] = i
+			}
+		}
+	}
+	for k := 0; k < count; k++ {
+		for a := 0; a < count; a++ {
+			for b := 0; b < count; b++ {
+				if d := dist[a][k] + dist[k][b]; d < dist[a][b] {
+					dist[a][b] = d
+					n.next[a][b] = n.next[a][k]
+				}
+			}
+		}
+	}
+	return n
+}
+
+// takeoff finds where on surface a a body moving like m can jump from to
+// come down safely on surface b, trying the end nearest b first
+func (n *navGraph) takeoff(m jumpModel, a, b int) (float64, bool) {
+	from := n.landings[a]
+	first, last, step := from.X-float64(m.Width)+0.5, from.X+from.Width-0.5, 0.5
+	if n.surfaces[b].X < from.X {
+		first, last, step = last, first, -0.5
+	}
+	for x := first; (x-last)*step <= 0; x += step {
+		aim := n.steerOnto(b, x)
+		if i, at := n.fall(m, x, from.Y, m.JumpSpeed, aim); i == b && n.safe(b, at, m.Width) {
+			return x, true
+		}
+	}
+	return 0, false
+}
+
+// fall follows a body moving like m from x, with its feet at row feet and
+// moving vel rows a second, as it heads for aim. It returns the surface the
+// body comes down on and where, taking each frame as the enemy update does
+// (-1 if it falls out of the world or through a fire vent's flames).
+func (n *navGraph) fall(m jumpModel, x, feet, vel, aim float64) (int, float64) {
+	dt := FrameDuration.Seconds()
+	step := m.AirSpeed * dt
+	bottom := 0.0
+	for _, s := range n.surfaces {
+		bottom = math.Max(bottom, s.Y)
+	}
+	for feet <= bottom {
+		if math.Abs(aim-x) > step {
+			x += math.Copysign(step, aim-x)
+		} else {
+			x = aim
+		}
+		vel += m.Gravity * dt
+		feet += vel * dt
+		top := feet - float64(m.Height)
+		if vel > 0 {
+			// Going past the end of a surface by a hair counts as landing on
+			// it, since rounding in the real update can tip it either way
+			best := -1
+			for i, s := range n.surfaces {
+				if x < s.X+s.Width+navHair && x+float64(m.Width) > s.X-navHair && feet >= s.Y && top < s.Y &&
+					(best < 0 || s.Y < n.surfaces[best].Y) {
+					best = i
+				}
+			}
+			if best >= 0 {
+				return best, x
+			}
+		}
+		for _, f := range n.flames {
+			if x < f.X+f.Width && x+float64(m.Width) > f.X && top < f.Y+f.Height && feet > f.Y {
+				return -1, x
+			}
+		}
+	}
+	return -1, x
+}
+
+// safe reports whether a body width wide at x is on the landing of surface i
+func (n *navGraph) safe(i int, x float64, width int) bool {
+	l := n.landings[i]
+	return x > l.X-float64(width) && x < l.X+l.Width
+}
+
+// under is the surface a body spanning [x, x+width) with its feet at row
+// feet is standing on, or would land on if it fell straight down (-1 if
+// none)
+func (n *navGraph) under(x float64, width int, feet float64) int {
+	best := -1
+	for i, s := range n.surfaces {
+		if x >= s.X+s.Width || x+float64(width) <= s.X || s.Y < feet-navLevel {
+			continue
+		}
+		if best < 0 || s.Y < n.surfaces[best].Y {
+			best = i
+		}
+	}
+	return best
+}
+
+// standingOn is the surface a body is standing on, or -1 in the air
+func (n *navGraph) standingOn(x float64, width int, feet float64) int {
+	if i := n.under(x, width, feet); i >= 0 && math.Abs(n.surfaces[i].Y-feet) < navLevel {
+		return i
+	}
+	return -1
+}
+
+// routeEnemy decides where an enemy chasing target goes next: the X to head
+// for and whether to jump now. same is true when the enemy is on the
+// target's surface, or in the air without a route, and should just go
+// straight for it.
+func (g *Game) routeEnemy(e *Enemy, target *Player) (x float64, jump, same bool) {
+	n := g.navGraph()
+	feet := e.Pos.Y + float64(e.Height)
+	from := -1
+	if e.Vel.Y == 0 { // Landing stops it dead, so anything else is in the air
+		from = n.standingOn(e.Pos.X, e.Width, feet)
+	}
+	if from < 0 {
+		// In the air: steer for the surface it set off for
+		if e.NavTo >= 0 && e.NavTo < len(n.surfaces) {
+			x, _ := n.aim(e, e.NavTo, target.Pos.X, e.Vel.Y)
+			return x, false, false
+		}
+		return target.Pos.X, false, true
+	}
+	e.NavTo = -1
+
+	to := n.under(target.Pos.X, target.Width, target.Pos.Y+float64(target.Height))
+	if to == from {
+		return target.Pos.X, false, true
+	}
+	if to < 0 || n.next[from][to] < 0 {
+		// No way there: wait as near as it safely can
+		return n.steerOnto(from, target.Pos.X), false, false
+	}
+	edge := n.edges[from][n.next[from][to]]
+	switch edge.Kind {
+	case navJump:
+		if x, ok := n.aim(e, edge.To, target.Pos.X, n.model.JumpSpeed); ok {
+			e.NavTo = edge.To
+			return x, true, false
+		}
+		return edge.X, false, false // Not from here
+	case navDrop:
+		e.NavTo = edge.To
+		return edge.X, false, false
+	}
+	return n.steerOnto(edge.To, target.Pos.X), false, false
+}
+
+// aim picks where an enemy moving vel rows a second should head for to come
+// down safely on surface i: above the spot nearest x if it can, else the
+// nearest spot it can. If neither works it reports false, and the enemy is
+// best off falling straight down.
+func (n *navGraph) aim(e *Enemy, i int, x, vel float64) (float64, bool) {
+	feet := e.Pos.Y + float64(e.Height)
+	for _, try := range []float64{n.steerOnto(i, x), n.steerOnto(i, e.Pos.X)} {
+		if to, at := n.fall(n.model, e.Pos.X, feet, vel, try); to == i && n.safe(i, at, e.Width) {
+			return try, true
+		}
+	}
+	return e.Pos.X, false
+}
+
+// steerOnto is the X nearest x where an enemy would be safely over surface i
+func (n *navGraph) steerOnto(i int, x float64) float64 {
+	s := n.landings[i]
+	return math.Max(s.X, math.Min(x, s.X+s.Width-float64(EnemyWidth)))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// edgeBetween finds the move from surface a to surface b
+func edgeBetween(n *navGraph, a, b int) (navEdge, bool) {
+	for _, e := range n.edges[a] {
+		if e.To == b {
+			return e, true
+		}
+	}
+	return navEdge{}, false
+}
+
+func TestNavEdges(t *testing.T) {
+	const (
+		ground = iota
+		side   // Same height as the ground, touching its right end
+		low    // Platform a jump up from the ground
+		high   // Platform far too high to jump to
+	)
+	surfaces := []Platform{
+		{X: -4, Y: 29, Width: 54, Height: 1},
+		{X: 50, Y: 29, Width: 54, Height: 1},
+		{X: 20, Y: 24, Width: 12, Height: 1},
+		{X: 20, Y: 8, Width: 12, Height: 1},
+	}
+	n := buildNavGraph(surfaces, surfaces, nil, 100, enemyJump)
+
+	tests := []struct {
+		name     string
+		from, to int
+		kind     int // -1 for no edge
+	}{
+		{"walk across", ground, side, navWalk},
+		{"walk back", side, ground, navWalk},
+		{"jump up", ground, low, navJump},
+		{"drop down", low, ground, navDrop},
+		{"too high to jump", ground, high, -1},
+		{"too high from the platform below", low, high, -1},
+		{"drop from far up", high, ground, navDrop},
+	}
+	for _, tt := range tests {
+		e, ok := edgeBetween(n, tt.from, tt.to)
+		switch {
+		case tt.kind < 0 && ok:
+			t.Errorf("%s: edge %+v, want none", tt.name, e)
+		case tt.kind >= 0 && !ok:
+			t.Errorf("%s: no edge", tt.name)
+		case ok && e.Kind != tt.kind:
+			t.Errorf("%s: edge kind %d, want %d", tt.name, e.Kind, tt.kind)
+		}
+	}
+
+	// Routes chain edges together, and don't exist where no edges lead
+	if i := n.next[side][low]; i < 0 || n.edges[side][i].To != ground {
+		t.Errorf("route from the side to the low platform doesn't go by the ground")
+	}
+	if n.next[ground][high] >= 0 || n.next[low][high] >= 0 {
+		t.Errorf("a route leads to the platform nothing can reach")
+	}
+}
+
+func TestNavSurfacesSplitAtHazards(t *testing.T) {
+	g := newTestGame(t)
+	g.platforms = []Platform{{X: 20, Y: 22, Width: 30, Height: 1}}
+	g.hazards = []Hazard{
+		{Kind: HazardSpikes, X: 30, Y: 21, Width: 5}, // On the platform
+		{Kind: HazardPit, X: 60, Width: 6},
+	}
+	g.worldWidth = 100
+	ground := float64(g.groundY)
+
+	surfaces, landings := g.navSurfaces()
+	want := []struct{ surface, landing Platform }{
+		// Either side of the spikes, landing clear of them
+		{Platform{X: 20, Y: 22, Width: 10, Height: 1}, Platform{X: 20, Y: 22, Width: 6, Height: 1}},
+		{Platform{X: 35, Y: 22, Width: 15, Height: 1}, Platform{X: 39, Y: 22, Width: 11, Height: 1}},
+		// Either side of the pit, landing with the middle of the body clear of
+		// it and the whole body inside the world
+		{Platform{X: -4, Y: ground, Width: 64, Height: 1}, Platform{X: 0, Y: ground, Width: 58, Height: 1}},
+		{Platform{X: 66, Y: ground, Width: 38, Height: 1}, Platform{X: 68, Y: ground, Width: 32, Height: 1}},
+	}
+	if len(surfaces) != len(want) {
+		t.Fatalf("%d surfaces %v, want %d", len(surfaces), surfaces, len(want))
+	}
+	for i, w := range want {
+		if surfaces[i] != w.surface {
+			t.Errorf("surface %d is %+v, want %+v", i, surfaces[i], w.surface)
+		}
+		if landings[i] != w.landing {
+			t.Errorf("landing %d is %+v, want %+v", i, landings[i], w.landing)
+		}
+	}
+}
+
+func TestRouteEnemy(t *testing.T) {
+	g := newTestGame(t)
+	g.worldWidth = 100
+	ground := float64(g.groundY)
+	low := Platform{X: 20, Y: ground - 5, Width: 12, Height: 1}
+	high := Platform{X: 60, Y: ground - 20, Width: 12, Height: 1}
+	g.platforms = []Platform{low, high}
+	g.hazards = nil
+	g.nav = nil
+
+	enemy := func(x float64) *Enemy {
+		return &Enemy{Pos: Vec2{X: x, Y: ground - EnemyHeight}, Width: EnemyWidth, Height: EnemyHeight, Active: true, NavTo: -1}
+	}
+	target := func(on Platform) *Player {
+		return &Player{Pos: Vec2{X: on.X + 4, Y: on.Y - PlayerHeight}, Width: PlayerWidth, Height: PlayerHeight}
+	}
+
+	// On the same surface it goes straight for the player
+	if x, jump, same := g.routeEnemy(enemy(80), target(Platform{X: 50, Y: ground})); !same || jump || x != 54 {
+		t.Errorf("same surface: x %v, jump %v, same %v", x, jump, same)
+	}
+
+	// Heads for the take-off point for the low platform, then jumps from it
+	e := enemy(90)
+	x, jump, _ := g.routeEnemy(e, target(low))
+	for i := 0; !jump && i < 3; i++ {
+		e = enemy(x)
+		x, jump, _ = g.routeEnemy(e, target(low))
+	}
+	if !jump || e.NavTo != 0 {
+		t.Errorf("didn't jump for the low platform (x %v, NavTo %d)", x, e.NavTo)
+	}
+
+	// Nothing reaches the high platform, so it waits below, as near as it can
+	x, jump, same := g.routeEnemy(enemy(10), target(high))
+	if jump || same || math.Abs(x-(high.X+4)) > 0.001 {
+		t.Errorf("unreachable target: x %v, jump %v, same %v, want to wait at %v", x, jump, same, high.X+4)
+	}
+}
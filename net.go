@@ -0,0 +1,614 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Networked play: the host runs the only simulation and streams a snapshot of
+// it to every client each frame; clients send back the actions their player
+// presses. Messages are JSON objects, one per line. A client opens with "join"
+// to play or "watch" to spectate.
+
+const maxPlayers = 4 // The host plus up to three clients
+
+// netMessage is everything that goes over the wire
+type netMessage struct {
+	Type     string    `json:"type"`               // "join", "watch", "welcome", "full", "state" or "input"
+	Slot     int       `json:"slot,omitempty"`     // welcome: the client's player index (spectatorSlot to watch)
+	Action   Action    `json:"action,omitempty"`   // input: the action pressed
+	Snapshot *snapshot `json:"snapshot,omitempty"` // state: the game as the host sees it
+}
+
+// snapshot is the part of the host's game a client needs to draw it
+type snapshot struct {
+	InMenu      bool            `json:"menu,omitempty"`
+	GameOver    bool            `json:"over,omitempty"`
+	StageClear  bool            `json:"clear,omitempty"`
+	RunTime     float64         `json:"runTime,omitempty"`
+	Clock       float64         `json:"clock"` // Seconds since the run started (fire vents run off this)
+	Height      int             `json:"height"`
+	WorldWidth  int             `json:"worldWidth"`
+	Goal        float64         `json:"goal,omitempty"`
+	Platforms   []Platform      `json:"platforms"`
+	Hazards     []Hazard        `json:"hazards,omitempty"`
+	Players     []netPlayer     `json:"players"`
+	Enemies     []netEnemy      `json:"enemies,omitempty"`
+	Projectiles []netProjectile `json:"projectiles,omitempty"`
+	Corpses     []netEnemy      `json:"corpses,omitempty"`
+	Particles   []netParticle   `json:"particles,omitempty"`
+	Blood       []netParticle   `json:"blood,omitempty"`
+	RedGround   map[int]int     `json:"redGround,omitempty"`
+	RedPlatform map[int]int     `json:"redPlatform,omitempty"`
+	Match       *Match          `json:"match,omitempty"`
+}
+
+type netPlayer struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Facing int     `json:"facing"`
+	Score  int     `json:"score"`
+	Lives  int     `json:"lives"`
+	Dead   bool    `json:"dead,omitempty"`
+	Safe   bool    `json:"safe,omitempty"` // Just respawned (drawn blinking)
+}
+
+type netEnemy struct {
+	ID     int     `json:"id,omitempty"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Facing int     `json:"facing"`
+}
+
+type netProjectile struct {
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Owner int     `json:"owner"`
+	Frame int     `json:"frame"`
+}
+
+type netParticle struct {
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Char    rune    `json:"char"`
+	EnemyID int     `json:"id,omitempty"`
+	Red     bool    `json:"red,omitempty"`
+	Fading  bool    `json:"fading,omitempty"` // About to disappear (drawn flashing)
+}
+
+// netInput is an action a client pressed, tagged with the client's player
+type netInput struct {
+	Slot   int
+	Action Action
+}
+
+// netHost accepts clients and hands each one a player slot
+type netHost struct {
+	listener net.Listener
+	inputs   chan netInput
+
+	mu         sync.Mutex
+	clients    map[int]*hostClient // Keyed by player slot
+	spectators map[*hostClient]bool
+}
+
+type hostClient struct {
+	conn net.Conn
+	send chan []byte // Encoded messages waiting to be written
+}
+
+// startHost listens for clients on addr
+func startHost(addr string) (*netHost, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	h := &netHost{
+		listener:   ln,
+		inputs:     make(chan netInput, 64),
+		clients:    make(map[int]*hostClient),
+		spectators: make(map[*hostClient]bool),
+	}
+	go h.acceptLoop()
+	return h, nil
+}
+
+func (h *netHost) acceptLoop() {
+	for {
+		conn, err := h.listener.Accept()
+		if err != nil {
+			return
+		}
+		go h.serve(conn)
+	}
+}
+
+// serve gives a new client the lowest free player slot, then reads its
+// inputs until it disconnects
+func (h *netHost) serve(conn net.Conn) {
+	defer conn.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	var hello netMessage
+	if err := dec.Decode(&hello); err != nil {
+		return
+	}
+	if hello.Type == "watch" {
+		h.serveSpectator(conn, dec)
+		return
+	}
+
+	h.mu.Lock()
+	slot := 0
+	for i := 1; i < maxPlayers; i++ {
+		if h.clients[i] == nil {
+			slot = i
+			break
+		}
+	}
+	if slot == 0 {
+		h.mu.Unlock()
+		json.NewEncoder(conn).Encode(netMessage{Type: "full"})
+		return
+	}
+	c := &hostClient{conn: conn, send: make(chan []byte, 4)}
+	h.clients[slot] = c
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, slot)
+		h.mu.Unlock()
+		close(c.send)
+	}()
+
+	if err := json.NewEncoder(conn).Encode(netMessage{Type: "welcome", Slot: slot}); err != nil {
+		return
+	}
+	go c.writeLoop()
+
+	for {
+		var msg netMessage
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+		if msg.Type == "input" && msg.Action >= 0 && msg.Action < numActions {
+			h.inputs <- netInput{Slot: slot, Action: msg.Action}
+		}
+	}
+}
+
+// serveSpectator streams the game to a spectator until it disconnects.
+// Spectators don't get a player slot, and anything they send is ignored.
+func (h *netHost) serveSpectator(conn net.Conn, dec *json.Decoder) {
+	c := &hostClient{conn: conn, send: make(chan []byte, 4)}
+	h.mu.Lock()
+	h.spectators[c] = true
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.spectators, c)
+		h.mu.Unlock()
+		close(c.send)
+	}()
+
+	if err := json.NewEncoder(conn).Encode(netMessage{Type: "welcome", Slot: spectatorSlot}); err != nil {
+		return
+	}
+	go c.writeLoop()
+
+	for {
+		var msg netMessage
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+	}
+}
+
+// writeLoop writes queued messages until send is closed
+func (c *hostClient) writeLoop() {
+	for data := range c.send {
+		if _, err := c.conn.Write(data); err != nil {
+			c.conn.Close()
+		}
+	}
+}
+
+// connected reports whether a client holds a player slot
+func (h *netHost) connected(slot int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.clients[slot] != nil
+}
+
+// highestSlot is the highest player slot a client holds (0 if none)
+func (h *netHost) highestSlot() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	highest := 0
+	for slot := range h.clients {
+		highest = max(highest, slot)
+	}
+	return highest
+}
+
+// clientCount is how many clients are connected to play
+func (h *netHost) clientCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// spectatorCount is how many clients are connected to watch
+func (h *netHost) spectatorCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.spectators)
+}
+
+// broadcast queues a message for every client and spectator. A client that
+// isn't keeping up misses the message rather than holding up the game.
+func (h *netHost) broadcast(msg netMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range h.clients {
+		select {
+		case c.send <- data:
+		default:
+		}
+	}
+	for c := range h.spectators {
+		select {
+		case c.send <- data:
+		default:
+		}
+	}
+}
+
+// isRemote reports whether player i is controlled by a network client
+func (g *Game) isRemote(i int) bool {
+	return g.host != nil && i > 0 && g.host.connected(i)
+}
+
+// pollNetwork applies the actions clients pressed since the last frame
+func (g *Game) pollNetwork() {
+	if g.host == nil {
+		return
+	}
+	for {
+		select {
+		case in := <-g.host.inputs:
+			if !g.inMenu && in.Slot < len(g.players) {
+				g.pressAction(&g.players[in.Slot], in.Action)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// broadcastState sends the current frame to every client and spectator
+func (g *Game) broadcastState() {
+	hosting := g.host != nil && g.host.clientCount()+g.host.spectatorCount() > 0
+	watched := g.live != nil && g.live.watched()
+	if !hosting && !watched {
+		return
+	}
+	s := g.snapshot()
+	if hosting {
+		g.host.broadcast(netMessage{Type: "state", Snapshot: s})
+	}
+	if watched {
+		g.live.publish(s)
+	}
+}
+
+func (g *Game) snapshot() *snapshot {
+	now := g.now
+	s := &snapshot{
+		InMenu:      g.inMenu,
+		GameOver:    g.gameOver,
+		StageClear:  g.stageClear,
+		RunTime:     g.runTime.Seconds(),
+		Clock:       now.Sub(g.runStart).Seconds(),
+		Height:      g.height,
+		WorldWidth:  g.worldWidth,
+		Goal:        g.goalX,
+		Platforms:   g.platforms,
+		Hazards:     g.hazards,
+		RedGround:   g.redGroundTiles,
+		RedPlatform: g.redPlatformTiles,
+		Match:       g.match,
+	}
+	for _, p := range g.players {
+		s.Players = append(s.Players, netPlayer{
+			X: p.Pos.X, Y: p.Pos.Y, Facing: p.Facing,
+			Score: p.Score, Lives: p.Lives, Dead: p.Dead, Safe: now.Before(p.SafeUntil),
+		})
+	}
+	for _, e := range g.enemies {
+		if e.Active {
+			s.Enemies = append(s.Enemies, netEnemy{ID: e.ID, X: e.Pos.X, Y: e.Pos.Y, Facing: e.Facing})
+		}
+	}
+	for _, p := range g.projectiles {
+		if p.Active {
+			s.Projectiles = append(s.Projectiles, netProjectile{X: p.Pos.X, Y: p.Pos.Y, Owner: p.Owner, Frame: p.Frame})
+		}
+	}
+	for _, c := range g.corpses {
+		if c.Active {
+			s.Corpses = append(s.Corpses, netEnemy{X: c.Pos.X, Y: c.Pos.Y, Facing: c.Facing})
+		}
+	}
+	for _, p := range g.deathParticles {
+		if p.Active {
+			fading := p.EnemyID != 0 && p.OnGround && now.Sub(p.GroundTime) >= 2500*time.Millisecond
+			s.Particles = append(s.Particles, netParticle{X: p.Pos.X, Y: p.Pos.Y, Char: p.Char, EnemyID: p.EnemyID, Red: p.IsRed, Fading: fading})
+		}
+	}
+	for _, p := range g.bloodParticles {
+		if p.Active {
+			s.Blood = append(s.Blood, netParticle{X: p.Pos.X, Y: p.Pos.Y, Char: p.Char, EnemyID: p.EnemyID})
+		}
+	}
+	return s
+}
+
+// netClient is the connection from a client to the host
+type netClient struct {
+	conn   net.Conn
+	slot   int // Player index, or spectatorSlot when watching
+	states chan *snapshot
+	err    error // Why the connection ended, once states is closed
+}
+
+// joinHost connects to a host and waits to be given a player slot
+func joinHost(addr string) (*netClient, error) {
+	return connectHost(addr, "join")
+}
+
+// watchHost connects to a host as a spectator
+func watchHost(addr string) (*netClient, error) {
+	return connectHost(addr, "watch")
+}
+
+func connectHost(addr, hello string) (*netClient, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(conn).Encode(netMessage{Type: hello}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	var msg netMessage
+	if err := dec.Decode(&msg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if msg.Type != "welcome" {
+		conn.Close()
+		return nil, fmt.Errorf("%s: game is full", addr)
+	}
+
+	c := &netClient{conn: conn, slot: msg.Slot, states: make(chan *snapshot, 8)}
+	go func() {
+		defer close(c.states)
+		for {
+			var msg netMessage
+			if err := dec.Decode(&msg); err != nil {
+				c.err = err
+				return
+			}
+			if msg.Type == "state" && msg.Snapshot != nil {
+				c.states <- msg.Snapshot
+			}
+		}
+	}()
+	return c, nil
+}
+
+func (c *netClient) sendAction(action Action) error {
+	return json.NewEncoder(c.conn).Encode(netMessage{Type: "input", Action: action})
+}
+
+// runClient plays on a remote host: keys are sent to the host, and the
+// snapshots it sends back are drawn with the usual render code. Players and
+// enemies are interpolated between the last two snapshots so they move
+// smoothly even when snapshots arrive unevenly.
+func (g *Game) runClient(c *netClient) error {
+	g.client = c
+	if c.slot == spectatorSlot {
+		g.watching = c.conn.RemoteAddr().String()
+	}
+	events := make(chan tcell.Event, 10)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			ev := g.screen.PollEvent()
+			if ev == nil {
+				return // Screen finalized
+			}
+			select {
+			case events <- ev:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(FrameDuration)
+	defer ticker.Stop()
+	defer c.conn.Close()
+
+	var buf snapshotBuffer
+	for {
+		select {
+		case ev := <-events:
+			switch ev := ev.(type) {
+			case *tcell.EventKey:
+				if ev.Key() == tcell.KeyEscape {
+					return nil
+				}
+				if ev.Key() == tcell.KeyTab {
+					g.bloodColorMode = (g.bloodColorMode + 1) % 4
+				}
+				if c.slot == spectatorSlot {
+					continue // Spectators only watch
+				}
+				// Either set of controls drives the client's ninja
+				for i := range playerControls {
+					if action, ok := playerControls[i].actionFor(ev); ok {
+						if err := c.sendAction(action); err != nil {
+							return err
+						}
+						break
+					}
+				}
+			case *tcell.EventResize:
+				g.width, g.height = g.screen.Size()
+			}
+		case s, ok := <-c.states:
+			if !ok {
+				return fmt.Errorf("lost connection to host: %v", c.err)
+			}
+			buf.push(s)
+		case <-ticker.C:
+			g.drawSnapshots(&buf)
+		}
+	}
+}
+
+// applySnapshot loads a snapshot into the client's game. Players and enemies
+// are placed alpha of the way from where they were in prev to where they are
+// in cur.
+func (g *Game) applySnapshot(prev, cur *snapshot, alpha float64) {
+	alpha = min(max(alpha, 0), 1)
+	lerp := func(a, b float64) float64 { return a + (b-a)*alpha }
+	// Clients don't run the simulation, so their clock just follows the wall
+	// clock; the host's timers arrive relative to it
+	g.now = time.Now()
+	now := g.now
+
+	g.inMenu = cur.InMenu
+	g.gameOver = cur.GameOver
+	g.stageClear = cur.StageClear
+	g.runTime = time.Duration(cur.RunTime * float64(time.Second))
+	g.runStart = now.Add(-time.Duration(cur.Clock * float64(time.Second)))
+	// The host's screen may be taller or shorter than this one, so everything
+	// moves down by dy to put the host's ground on the bottom row here
+	dy := float64(g.height - cur.Height)
+	g.groundY = g.height - 1
+	g.worldWidth = cur.WorldWidth
+	g.goalX = cur.Goal
+	g.platforms = g.platforms[:0]
+	for _, p := range cur.Platforms {
+		p.Y += dy
+		g.platforms = append(g.platforms, p)
+	}
+	g.hazards = g.hazards[:0]
+	for _, h := range cur.Hazards {
+		h.Y += dy
+		g.hazards = append(g.hazards, h)
+	}
+	g.redGroundTiles = cur.RedGround
+	g.redPlatformTiles = cur.RedPlatform
+	g.match = cur.Match
+
+	g.players = g.players[:0]
+	for i, np := range cur.Players {
+		p := g.newPlayer(i, Vec2{X: np.X, Y: np.Y + dy}, np.Lives)
+		if prev != nil && i < len(prev.Players) && !prev.Players[i].Dead {
+			p.Pos = Vec2{X: lerp(prev.Players[i].X, np.X), Y: lerp(prev.Players[i].Y, np.Y) + dy}
+		}
+		p.Facing = np.Facing
+		p.Score = np.Score
+		p.Dead = np.Dead
+		if np.Safe {
+			p.SafeUntil = now.Add(time.Second)
+		}
+		g.players = append(g.players, p)
+	}
+
+	var before map[int]netEnemy
+	if prev != nil {
+		before = make(map[int]netEnemy, len(prev.Enemies))
+		for _, e := range prev.Enemies {
+			before[e.ID] = e
+		}
+	}
+	g.enemies = g.enemies[:0]
+	for _, ne := range cur.Enemies {
+		pos := Vec2{X: ne.X, Y: ne.Y + dy}
+		if old, ok := before[ne.ID]; ok {
+			pos = Vec2{X: lerp(old.X, ne.X), Y: lerp(old.Y, ne.Y) + dy}
+		}
+		g.enemies = append(g.enemies, Enemy{ID: ne.ID, Pos: pos, Facing: ne.Facing, Width: EnemyWidth, Height: EnemyHeight, Active: true})
+	}
+
+	g.projectiles = g.projectiles[:0]
+	for _, np := range cur.Projectiles {
+		g.projectiles = append(g.projectiles, Projectile{Pos: Vec2{X: np.X, Y: np.Y + dy}, Owner: np.Owner, Frame: np.Frame, Active: true})
+	}
+	g.corpses = g.corpses[:0]
+	for _, nc := range cur.Corpses {
+		g.corpses = append(g.corpses, Corpse{Pos: Vec2{X: nc.X, Y: nc.Y + dy}, Facing: nc.Facing, Active: true})
+	}
+	g.deathParticles = g.deathParticles[:0]
+	for _, np := range cur.Particles {
+		p := DeathParticle{Pos: Vec2{X: np.X, Y: np.Y + dy}, Char: np.Char, EnemyID: np.EnemyID, IsRed: np.Red, Active: true}
+		if np.Fading {
+			p.OnGround = true
+			p.GroundTime = now.Add(-3 * time.Second)
+		}
+		g.deathParticles = append(g.deathParticles, p)
+	}
+	g.bloodParticles = g.bloodParticles[:0]
+	for _, np := range cur.Blood {
+		g.bloodParticles = append(g.bloodParticles, BloodParticle{Pos: Vec2{X: np.X, Y: np.Y + dy}, Char: np.Char, EnemyID: np.EnemyID, Active: true})
+	}
+
+	g.updateCamera()
+}
+
+// renderClient draws the host's game, or a waiting screen while the host is
+// in the menu
+func (g *Game) renderClient() {
+	if !g.inMenu {
+		g.render()
+		return
+	}
+
+	g.screen.Clear()
+	g.drawGround()
+	g.drawHazards()
+	g.drawPlatforms()
+
+	if g.watching != "" {
+		title := "Watching " + g.watching
+		g.drawText((g.width-len([]rune(title)))/2, g.height/2-2, title, tcell.StyleDefault.Foreground(tcell.ColorWhite))
+	} else {
+		style := tcell.StyleDefault.Foreground(playerColors[g.client.slot%len(playerColors)])
+		title := fmt.Sprintf("Connected as P%d", g.client.slot+1)
+		g.drawText((g.width-len(title))/2, g.height/2-2, title, style)
+	}
+	waiting := "Waiting for the game to start (ESC to leave)"
+	g.drawText((g.width-len(waiting))/2, g.height/2, waiting, tcell.StyleDefault)
+	g.screen.Show()
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestClientShowsHostOnItsOwnGround(t *testing.T) {
+	host := newTestGame(t)
+	pressKey(host, tcell.KeyRune, ' ')
+	host.update(1.0 / 30)
+	snap := host.snapshot()
+	platformY := snap.Platforms[0].Y
+
+	// A shorter terminal than the host's sees everything moved up with the ground
+	client := newTestGameSized(t, 100, 20)
+	client.applySnapshot(nil, snap, 1)
+	client.applySnapshot(snap, snap, 1)
+	if client.groundY != 19 {
+		t.Errorf("ground at row %d, want 19", client.groundY)
+	}
+	if got, want := client.players[0].Pos.Y, host.players[0].Pos.Y-10; got != want {
+		t.Errorf("player at row %v, want %v", got, want)
+	}
+	if got, want := client.platforms[0].Y, platformY-10; got != want {
+		t.Errorf("platform at row %v, want %v", got, want)
+	}
+	if snap.Platforms[0].Y != platformY {
+		t.Error("drawing the snapshot changed it")
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Action is something a player can do. Keys are bound to actions per player,
+// so two players can share one keyboard.
+type Action int
+
+const (
+	ActionLeft Action = iota
+	ActionRight
+	ActionJump
+	ActionThrow
+	numActions
+)
+
+// keyBinding is a special key, or a rune when Key is tcell.KeyRune
+type keyBinding struct {
+	Key  tcell.Key
+	Rune rune
+}
+
+// Controls maps each action to the keys that trigger it
+type Controls [numActions][]keyBinding
+
+// playerControls are the key bindings of player one and player two
+var playerControls = []Controls{
+	{
+		ActionLeft:  {{Key: tcell.KeyLeft}},
+		ActionRight: {{Key: tcell.KeyRight}},
+		ActionJump:  {{Key: tcell.KeyUp}},
+		ActionThrow: {{Key: tcell.KeyRune, Rune: ' '}},
+	},
+	{
+		ActionLeft:  {{Key: tcell.KeyRune, Rune: 'a'}, {Key: tcell.KeyRune, Rune: 'A'}},
+		ActionRight: {{Key: tcell.KeyRune, Rune: 'd'}, {Key: tcell.KeyRune, Rune: 'D'}},
+		ActionJump:  {{Key: tcell.KeyRune, Rune: 'w'}, {Key: tcell.KeyRune, Rune: 'W'}},
+		ActionThrow: {{Key: tcell.KeyRune, Rune: 'f'}, {Key: tcell.KeyRune, Rune: 'F'}},
+	},
+}
+
+// playerColors are the sprite colors of each player; the last two only play
+// over the network
+var playerColors = []tcell.Color{tcell.ColorBlue, tcell.ColorFuchsia, tcell.ColorOrange, tcell.ColorLime}
+
+const (
+	respawnDelay  = 2 * time.Second         // Time a co-op player stays down after losing a life
+	respawnGrace  = 1500 * time.Millisecond // Time a respawned player can't be hurt
+	keyTimeout    = 150 * time.Millisecond  // How long a key press counts as the key being held
+	playerSpacing = PlayerWidth + 2         // Gap between co-op players at the spawn point
+)
+
+// actionFor reports which action, if any, a key is bound to
+func (c *Controls) actionFor(ev *tcell.EventKey) (Action, bool) {
+	for action, bindings := range c {
+		for _, b := range bindings {
+			if ev.Key() == b.Key && (b.Key != tcell.KeyRune || ev.Rune() == b.Rune) {
+				return Action(action), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// handlePlayerKey routes a gameplay key to the local player it's bound to
+func (g *Game) handlePlayerKey(ev *tcell.EventKey) {
+	for i := range g.players {
+		if i >= len(playerControls) || g.isRemote(i) {
+			continue
+		}
+		if action, ok := playerControls[i].actionFor(ev); ok {
+			g.pressAction(&g.players[i], action)
+			return
+		}
+	}
+}
+
+// pressAction performs an action for a player. Movement and jumping are held
+// actions; they stay active for keyTimeout after the last press.
+func (g *Game) pressAction(p *Player, action Action) {
+	if p.Dead {
+		return
+	}
+	now := g.now
+	p.Pressed[action] = now
+	if action == ActionThrow && now.Sub(p.LastShot) > g.difficulty.ThrowCooldown {
+		g.throwShuriken(p)
+		p.LastShot = now
+	}
+}
+
+// holding reports whether a player is still holding an action's key
+func (p *Player) holding(action Action, now time.Time) bool {
+	return !p.Pressed[action].IsZero() && now.Sub(p.Pressed[action]) < keyTimeout
+}
+
+func (g *Game) throwShuriken(p *Player) {
+	center := Vec2{X: p.Pos.X + float64(p.Width/2), Y: p.Pos.Y + float64(p.Height/2)}
+	g.projectiles = append(g.projectiles, Projectile{
+		Pos:     center,
+		PrevPos: center,
+		Dir:     p.Facing,
+		Active:  true,
+		Frame:   0,
+		Owner:   p.Index,
+	})
+	g.events.publish(ProjectileFired{Owner: p.Index, Pos: center, Dir: p.Facing})
+}
+
+// multiplayerCount is how many players a co-op or versus game has: two on one
+// keyboard, or enough for everyone connected when hosting
+func (g *Game) multiplayerCount() int {
+	n := 2
+	if g.host != nil {
+		n = max(n, g.host.highestSlot()+1)
+	}
+	return n
+}
+
+// newPlayer creates player i standing at pos
+func (g *Game) newPlayer(i int, pos Vec2, lives int) Player {
+	return Player{
+		Index:            i,
+		Color:            playerColors[i%len(playerColors)],
+		Pos:              pos,
+		Vel:              Vec2{X: 0, Y: 0},
+		Facing:           1,
+		MoveDir:          0,
+		Width:            PlayerWidth,
+		Height:           PlayerHeight,
+		OnGround:         pos.Y >= float64(g.groundY-PlayerHeight),
+		OnPlatform:       false,
+		LastOnGroundTime: g.now,
+		Lives:            lives,
+	}
+}
+
+// spawnPos is where player i starts: the level's spawn point, with any second
+// player standing just to the right
+func (g *Game) spawnPos(i int) Vec2 {
+	pos := Vec2{X: float64(g.width / 2), Y: float64(g.groundY - PlayerHeight)}
+	if g.playerSpawn != nil {
+		pos = *g.playerSpawn
+	}
+	pos.X += float64(i * playerSpacing)
+	if pos.X+PlayerWidth > float64(g.worldWidth) {
+		pos.X = float64(g.worldWidth - PlayerWidth - i*playerSpacing)
+	}
+	return pos
+}
+
+// nearestPlayer returns the living player closest to pos, or nil if everyone
+// is down
+func (g *Game) nearestPlayer(pos Vec2) *Player {
+	var nearest *Player
+	best := 0.0
+	for i := range g.players {
+		p := &g.players[i]
+		if p.Dead {
+			continue
+		}
+		dx, dy := p.Pos.X-pos.X, p.Pos.Y-pos.Y
+		if d := dx*dx + dy*dy; nearest == nil || d < best {
+			nearest, best = p, d
+		}
+	}
+	return nearest
+}
+
+// killPlayer blows a player apart and takes a life. Outside versus, the game
+// ends once nobody is left standing or waiting to respawn. The cause is one of
+// the causes in causeText, and by the rival whose shuriken it was in versus.
+func (g *Game) killPlayer(p *Player, cause string, by *Player) {
+	if p.Dead || g.now.Before(p.SafeUntil) {
+		return
+	}
+	g.createPlayerDeathParticles(p)
+	p.Dead = true
+	p.Lives--
+	if p.Lives > 0 {
+		p.RespawnAt = g.now.Add(respawnDelay)
+	}
+	g.events.publish(PlayerHit{Player: p.Index, Pos: p.Pos, Cause: cause, By: playerIndex(by), LivesLeft: p.Lives})
+	if g.match != nil {
+		return // updateMatch decides how the round ends
+	}
+
+	for i := range g.players {
+		if !g.players[i].Dead || g.players[i].Lives > 0 {
+			return
+		}
+	}
+	g.gameOver = true
+}
+
+// updateRespawns brings back downed co-op players next to a living partner,
+// or at the spawn point if nobody is standing
+func (g *Game) updateRespawns() {
+	now := g.now
+	for i := range g.players {
+		p := &g.players[i]
+		if !p.Dead || p.Lives <= 0 || now.Before(p.RespawnAt) {
+			continue
+		}
+		pos := g.spawnPos(i)
+		if partner := g.nearestPlayer(p.Pos); partner != nil {
+			pos = partner.Pos
+		}
+		respawned := g.newPlayer(i, pos, p.Lives)
+		respawned.Score = p.Score
+		respawned.SafeUntil = now.Add(respawnGrace)
+		*p = respawned
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// saveVersion is bumped whenever the save format changes. Saves from other
+// versions are refused rather than loaded wrong.
+const saveVersion = 1
+
+// savedGame is an unfinished run, written when the player quits with ESC.
+// Timers are game clock times, so they pick up where they left off.
+type savedGame struct {
+	Version     int          `json:"version"`
+	Level       *Level       `json:"level"`
+	LevelIndex  int          `json:"levelIndex"`
+	Height      int          `json:"height"` // Screen height the run was laid out for
+	WorldWidth  int          `json:"worldWidth"`
+	CameraX     int          `json:"cameraX"`
+	Goal        float64      `json:"goal,omitempty"`
+	Platforms   []Platform   `json:"platforms"`
+	Hazards     []Hazard     `json:"hazards,omitempty"`
+	EnemySpawns []SpawnPoint `json:"enemySpawns,omitempty"`
+	PlayerSpawn *Vec2        `json:"playerSpawn,omitempty"`
+
+	NumPlayers     int             `json:"numPlayers"`
+	Match          *Match          `json:"match,omitempty"`
+	Players        []Player        `json:"players"`
+	Enemies        []Enemy         `json:"enemies"`
+	Projectiles    []Projectile    `json:"projectiles"`
+	Corpses        []Corpse        `json:"corpses"`
+	DeathParticles []DeathParticle `json:"deathParticles"`
+	BloodParticles []BloodParticle `json:"bloodParticles"`
+	RedGround      map[int]int     `json:"redGround"`
+	RedPlatform    map[int]int     `json:"redPlatform"`
+
+	EnemiesDefeated   int `json:"enemiesDefeated"`
+	EnemySpawnCounter int `json:"enemySpawnCounter"`
+	NextEnemyID       int `json:"nextEnemyID"`
+	EnemySerial       int `json:"enemySerial"`
+
+	Clock      time.Time  `json:"clock"` // Game clock when saved
+	RunStart   time.Time  `json:"runStart"`
+	RNGSeed    int64      `json:"rngSeed"`
+	RNGDraws   uint64     `json:"rngDraws"`
+	Director   *director  `json:"director,omitempty"`
+	Difficulty string     `json:"difficulty,omitempty"` // Name of the preset the run is played at
+	Recording  *Recording `json:"recording,omitempty"`
+	Ghost      *Ghost     `json:"ghost,omitempty"`
+	RunStats   Stats      `json:"runStats"`
+}
+
+// rngSource is the source behind Game.rng. math/rand has no way to save a
+// generator's state, so this counts the numbers drawn; restoring replays the
+// seed that far.
+type rngSource struct {
+	src   rand.Source
+	seed  int64
+	draws uint64
+}
+
+func newRNGSource(seed int64, draws uint64) *rngSource {
+	s := &rngSource{src: rand.NewSource(seed), seed: seed}
+	for s.draws < draws {
+		s.Int63()
+	}
+	return s
+}
+
+func (s *rngSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *rngSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.seed, s.draws = seed, 0
+}
+
+// seedRNG restarts the game's random numbers from a seed
+func (g *Game) seedRNG(seed int64, draws uint64) {
+	g.rngSource = newRNGSource(seed, draws)
+	g.rng = rand.New(g.rngSource)
+}
+
+// defaultSavePath is where an unfinished run is kept
+func defaultSavePath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "gninja", "save.json")
+}
+
+// canSave reports whether quitting now would save the run: only runs in
+// progress, and not editor playtests or network games
+func (g *Game) canSave() bool {
+	return g.savePath != "" && !g.inMenu && !g.gameOver && g.editor == nil && g.client == nil && g.host == nil
+}
+
+// saveRun writes the run in progress to the save file
+func (g *Game) saveRun() error {
+	s := savedGame{
+		Version:           saveVersion,
+		Level:             g.level,
+		LevelIndex:        g.levelIndex,
+		Height:            g.height,
+		WorldWidth:        g.worldWidth,
+		CameraX:           g.cameraX,
+		Goal:              g.goalX,
+		Platforms:         g.platforms,
+		Hazards:           g.hazards,
+		EnemySpawns:       g.enemySpawns,
+		PlayerSpawn:       g.playerSpawn,
+		NumPlayers:        g.numPlayers,
+		Match:             g.match,
+		Players:           g.players,
+		Enemies:           g.enemies,
+		Projectiles:       g.projectiles,
+		Corpses:           g.corpses,
+		DeathParticles:    g.deathParticles,
+		BloodParticles:    g.bloodParticles,
+		RedGround:         g.redGroundTiles,
+		RedPlatform:       g.redPlatformTiles,
+		EnemiesDefeated:   g.enemiesDefeated,
+		EnemySpawnCounter: g.enemySpawnCounter,
+		NextEnemyID:       g.nextEnemyID,
+		EnemySerial:       g.enemySerial,
+		Clock:             g.now,
+		RunStart:          g.runStart,
+		RNGSeed:           g.rngSource.seed,
+		Director:          &g.director,
+		Difficulty:        g.difficulty.Name,
+		RNGDraws:          g.rngSource.draws,
+		Recording:         g.recording,
+		Ghost:             g.ghost,
+		RunStats:          g.runStats,
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("saving game: %w", err)
+	}
+	if err := writeFileAtomic(g.savePath, data); err != nil {
+		return fmt.Errorf("saving game: %w", err)
+	}
+	g.hasSave = true
+	return nil
+}
+
+// loadRun resumes the saved run. The save is used up: quitting again saves
+// the run afresh.
+func (g *Game) loadRun() error {
+	data, err := os.ReadFile(g.savePath)
+	if err != nil {
+		return err
+	}
+	var s savedGame
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("saved game is damaged: %w", err)
+	}
+	if s.Version != saveVersion {
+		return fmt.Errorf("saved game is from another version of gninja (save format %d, this is %d)", s.Version, saveVersion)
+	}
+	if s.Level == nil || len(s.Players) == 0 {
+		return errors.New("saved game is incomplete")
+	}
+
+	g.level = s.Level
+	if s.LevelIndex >= 0 && s.LevelIndex < len(g.levels) {
+		g.levelIndex = s.LevelIndex
+	}
+	g.worldWidth = max(s.WorldWidth, g.width)
+	g.cameraX = s.CameraX
+	g.goalX = s.Goal
+	g.platforms = s.Platforms
+	g.hazards = s.Hazards
+	g.enemySpawns = s.EnemySpawns
+	g.playerSpawn = s.PlayerSpawn
+	g.numPlayers = s.NumPlayers
+	g.match = s.Match
+	g.players = s.Players
+	g.enemies = s.Enemies
+	g.projectiles = s.Projectiles
+	g.corpses = s.Corpses
+	g.deathParticles = s.DeathParticles
+	g.bloodParticles = s.BloodParticles
+	g.redGroundTiles = s.RedGround
+	g.redPlatformTiles = s.RedPlatform
+	if g.redGroundTiles == nil {
+		g.redGroundTiles = make(map[int]int)
+	}
+	if g.redPlatformTiles == nil {
+		g.redPlatformTiles = make(map[int]int)
+	}
+	g.enemiesDefeated = s.EnemiesDefeated
+	g.enemySpawnCounter = s.EnemySpawnCounter
+	g.nextEnemyID = s.NextEnemyID
+	g.enemySerial = s.EnemySerial
+	g.now = s.Clock
+	g.runStart = s.RunStart
+	g.seedRNG(s.RNGSeed, s.RNGDraws)
+	g.recording = s.Recording
+	g.ghost = s.Ghost
+	g.runStats = s.RunStats
+	g.lastDeath = ""
+	g.toasts = nil
+	g.loadProfile()
+	g.spawnRate = 0
+	if s.Difficulty != "" {
+		// Only for this run: the title screen's choice comes back after it
+		g.pickedDifficulty = g.difficulty
+		g.difficulty, _ = difficultyNamed(s.Difficulty)
+	}
+	g.nav = nil // Enemies may move differently at the run's difficulty
+	if s.Director != nil {
+		g.director = *s.Director
+	} else {
+		g.resetDirector()
+	}
+	g.inMenu = false
+	g.events.publish(RunStarted{Level: g.level.Name, Seed: g.rngSource.seed, Players: len(g.players), Versus: g.match != nil, Resumed: true, Difficulty: g.difficulty.Name})
+
+	// Line the run up with this screen's ground. Ghosts only replay at the
+	// height they were recorded at, so a resized run loses its ghost.
+	if s.Height != g.height {
+		g.shiftWorld(float64(g.groundY - (s.Height - 1)))
+		g.recording, g.ghost = nil, nil
+	}
+	g.clampCamera()
+
+	g.gameOver = false
+	g.stageClear = false
+	g.runTime = 0
+	g.newHighScore = -1
+	g.newBestRun = false
+
+	os.Remove(g.savePath)
+	g.hasSave = false
+	return nil
+}
+
+// shiftWorld moves the whole level and everything in it down by dy rows
+func (g *Game) shiftWorld(dy float64) {
+	g.nav = nil
+	for i := range g.platforms {
+		g.platforms[i].Y += dy
+	}
+	for i := range g.hazards {
+		g.hazards[i].Y += dy
+	}
+	for i := range g.enemySpawns {
+		g.enemySpawns[i].Y += dy
+	}
+	if g.playerSpawn != nil {
+		g.playerSpawn.Y += dy
+	}
+	for i := range g.players {
+		g.players[i].Pos.Y += dy
+	}
+	for i := range g.enemies {
+		g.enemies[i].Pos.Y += dy
+	}
+	for i := range g.projectiles {
+		g.projectiles[i].Pos.Y += dy
+		g.projectiles[i].PrevPos.Y += dy
+	}
+	for i := range g.corpses {
+		g.corpses[i].Pos.Y += dy
+	}
+	for i := range g.deathParticles {
+		g.deathParticles[i].Pos.Y += dy
+	}
+	for i := range g.bloodParticles {
+		g.bloodParticles[i].Pos.Y += dy
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// playFrame plays a frame of a scripted run: running right in spells and
+// throwing now and then, kept alive so the run lasts
+func playFrame(g *Game, frame int) {
+	p := &g.players[0]
+	p.SafeUntil = g.now.Add(time.Hour)
+	if frame%40 < 30 {
+		g.pressAction(p, ActionRight)
+	}
+	if frame%25 == 0 {
+		g.pressAction(p, ActionThrow)
+	}
+	g.update(1.0 / 30)
+}
+
+// enemySpawns lists the enemies in a game by ID and kind, with where they are
+// relative to the ground
+func enemySpawns(g *Game) []string {
+	var spawns []string
+	for _, e := range g.enemies {
+		spawns = append(spawns, fmt.Sprintf("%d %s %.3f,%.3f", e.ID, e.Kind, e.Pos.X, float64(g.groundY)-e.Pos.Y))
+	}
+	return spawns
+}
+
+func TestSaveAndContinue(t *testing.T) {
+	for _, height := range []int{30, 36} {
+		g := newTestGame(t)
+		g.savePath = filepath.Join(t.TempDir(), "save.json")
+		g.levelSeed = 7
+		pressKey(g, tcell.KeyRune, ' ')
+		for f := 0; f < 400; f++ {
+			playFrame(g, f)
+		}
+		if err := g.saveRun(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Continued on a screen of the height given, which moves the level
+		// down to its ground
+		resumed := newTestGameSized(t, 100, height)
+		resumed.savePath = g.savePath
+		resumed.hasSave = true
+		pressKey(resumed, tcell.KeyRune, 'c')
+		if resumed.inMenu || resumed.menuNote != "" {
+			t.Fatalf("height %d: run not continued: %s", height, resumed.menuNote)
+		}
+		if !resumed.now.Equal(g.now) {
+			t.Errorf("height %d: clock at %v, want %v", height, resumed.now, g.now)
+		}
+		if resumed.rngSource.draws != g.rngSource.draws {
+			t.Errorf("height %d: %d random numbers drawn, want %d", height, resumed.rngSource.draws, g.rngSource.draws)
+		}
+
+		for f := 400; f < 700; f++ {
+			playFrame(g, f)
+			playFrame(resumed, f)
+			want, got := enemySpawns(g), enemySpawns(resumed)
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Fatalf("height %d, frame %d: enemies %v, want %v", height, f, got, want)
+			}
+		}
+		if g.enemySerial == 0 {
+			t.Errorf("height %d: no enemies spawned to compare", height)
+		}
+	}
+}
+
+func TestDifficultyPutBackAfterContinuedRun(t *testing.T) {
+	g := newTestGame(t)
+	g.savePath = filepath.Join(t.TempDir(), "save.json")
+	hard := difficulties[2]
+	g.difficulty = hard
+	pressKey(g, tcell.KeyRune, ' ')
+	if err := g.saveRun(); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := newTestGame(t)
+	resumed.savePath = g.savePath
+	resumed.hasSave = true
+	pressKey(resumed, tcell.KeyRune, 'c')
+	if resumed.difficulty.Name != hard.Name {
+		t.Fatalf("continued at %s, want %s", resumed.difficulty.Name, hard.Name)
+	}
+	resumed.gameOver = true
+	pressKey(resumed, tcell.KeyEnter, 0)
+	if resumed.difficulty.Name != normalDifficulty.Name {
+		t.Errorf("back on the title screen at %s, want %s", resumed.difficulty.Name, normalDifficulty.Name)
+	}
+}
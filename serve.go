@@ -0,0 +1,393 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/crypto/ssh"
+)
+
+// serveSSH runs "gninja serve": every SSH connection gets a game of its own,
+// played on the client's terminal, and all of them share one high score table.
+// Connecting with the command "watch" spectates the games being played.
+func serveSSH(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("ssh", ":2222", "address to accept SSH connections on")
+	keyPath := flags.String("host-key", defaultHostKeyPath(), "SSH host key (created if missing)")
+	flags.Parse(args)
+
+	signer, err := loadHostKey(*keyPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log.Printf("serving gninja over SSH on %s", listener.Addr())
+
+	// Anyone can connect under any name, so profiles are only kept until the
+	// server stops: saving them would let the files grow without end. The
+	// high score table only ever keeps the best few.
+	srv := newSSHServer(signer, loadHighScores(highScoresPath()), loadStatsBook(""), loadAchievementBook(""), loadSettingsBook(""))
+	if err := srv.serve(listener); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// defaultHostKeyPath is where the server keeps its host key between runs
+func defaultHostKeyPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "ssh_host_ed25519"
+	}
+	return filepath.Join(base, "gninja", "ssh_host_ed25519")
+}
+
+// loadHostKey reads the server's host key, generating and saving a new one
+// the first time so clients see the same key on every run
+func loadHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		block, err := ssh.MarshalPrivateKey(key, "gninja host key")
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(block)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// sshServer hands out games to SSH sessions
+type sshServer struct {
+	config   *ssh.ServerConfig
+	scores   *HighScores
+	stats    *StatsBook
+	feats    *AchievementBook
+	settings *SettingsBook
+	games    *liveGames // Games being played, for spectators
+}
+
+func newSSHServer(signer ssh.Signer, scores *HighScores, stats *StatsBook, feats *AchievementBook, settings *SettingsBook) *sshServer {
+	// Anyone may play; the SSH user name is the name on the high score table
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+	return &sshServer{config: config, scores: scores, stats: stats, feats: feats, settings: settings, games: &liveGames{}}
+}
+
+// serve accepts connections until the listener is closed
+func (s *sshServer) serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *sshServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		return // Failed handshake
+	}
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, requests, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+		go s.handleSession(sconn.User(), channel, requests)
+	}
+}
+
+// handleSession waits for the client to ask for a terminal and a shell, then
+// runs a game on that terminal until the player quits or disconnects. Asking
+// to run "watch" instead of a shell opens the spectator view.
+func (s *sshServer) handleSession(user string, channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	tty := newSSHTty(channel)
+	defer close(tty.done)
+	started := false
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			term, cols, rows, ok := parsePtyRequest(req.Payload)
+			if ok {
+				tty.term = term
+				tty.resize(cols, rows)
+			}
+			req.Reply(ok, nil)
+		case "window-change":
+			if len(req.Payload) >= 8 {
+				tty.resize(binary.BigEndian.Uint32(req.Payload), binary.BigEndian.Uint32(req.Payload[4:]))
+			}
+		case "shell", "exec":
+			watch := false
+			if req.Type == "exec" {
+				var cmd struct{ Command string }
+				if ssh.Unmarshal(req.Payload, &cmd) != nil || strings.TrimSpace(cmd.Command) != "watch" {
+					req.Reply(false, nil)
+					continue
+				}
+				watch = true
+			}
+			if started {
+				req.Reply(false, nil)
+				continue
+			}
+			if tty.term == "" {
+				req.Reply(true, nil)
+				io.WriteString(channel, "gninja needs a terminal, try ssh -t\r\n")
+				channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{1}))
+				return
+			}
+			started = true
+			req.Reply(true, nil)
+			go func() {
+				s.play(user, tty, watch)
+				channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+				channel.Close()
+			}()
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// play runs one game on a session's terminal, or the spectator view
+func (s *sshServer) play(user string, tty *sshTty, watch bool) {
+	defer func() {
+		// One broken session mustn't take down everyone else's game
+		if r := recover(); r != nil {
+			log.Printf("session %s: %v", user, r)
+		}
+	}()
+
+	ti, err := tcell.LookupTerminfo(tty.term)
+	if err != nil {
+		ti, err = tcell.LookupTerminfo("xterm-256color")
+		if err != nil {
+			return
+		}
+	}
+	screen, err := tcell.NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		return
+	}
+	if err := screen.Init(); err != nil {
+		return
+	}
+	defer screen.Fini()
+
+	screen.SetStyle(tcell.StyleDefault.
+		Background(tcell.ColorDefault).
+		Foreground(tcell.ColorWhite))
+	screen.Clear()
+
+	game := NewGame(screen)
+	if watch {
+		game.runSpectator(s.games)
+		return
+	}
+	game.scores = s.scores
+	game.stats = s.stats
+	game.achievements = s.feats
+	game.settings = s.settings
+	game.playerName = user
+	if game.playerName == "" {
+		game.playerName = "anonymous"
+	}
+	game.loadSettings()
+	game.tuningPath = tuningPath()
+	game.watchTuning()
+	game.live = s.games.add(game.playerName)
+	defer s.games.remove(game.live)
+	game.run()
+}
+
+// parsePtyRequest reads the terminal type and size out of a "pty-req"
+func parsePtyRequest(payload []byte) (term string, cols, rows uint32, ok bool) {
+	var req struct {
+		Term   string
+		Cols   uint32
+		Rows   uint32
+		Width  uint32
+		Height uint32
+		Modes  string
+	}
+	if err := ssh.Unmarshal(payload, &req); err != nil {
+		return "", 0, 0, false
+	}
+	return req.Term, req.Cols, req.Rows, true
+}
+
+// sshTty is the terminal at the other end of an SSH session, as tcell sees it.
+// The client's terminal is already in raw mode, so starting and stopping is
+// free; the size comes from the pty and window-change requests.
+type sshTty struct {
+	channel ssh.Channel
+	term    string
+
+	mu       sync.Mutex
+	size     tcell.WindowSize
+	onResize func()
+	input    chan []byte
+	drained  chan struct{}
+	done     chan struct{}
+	err      error
+	pending  []byte
+}
+
+func newSSHTty(channel ssh.Channel) *sshTty {
+	t := &sshTty{
+		channel: channel,
+		input:   make(chan []byte),
+		drained: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop()
+	return t
+}
+
+// readLoop feeds what the client types to Read. Reading goes through a
+// goroutine so Drain can wake tcell's reader without closing the channel.
+func (t *sshTty) readLoop() {
+	defer close(t.input)
+	for {
+		buf := make([]byte, 128)
+		n, err := t.channel.Read(buf)
+		if n > 0 {
+			select {
+			case t.input <- buf[:n]:
+			case <-t.done:
+				return
+			}
+		}
+		if err != nil {
+			t.mu.Lock()
+			t.err = err
+			t.mu.Unlock()
+			return
+		}
+	}
+}
+
+func (t *sshTty) resize(cols, rows uint32) {
+	t.mu.Lock()
+	t.size = tcell.WindowSize{Width: int(cols), Height: int(rows)}
+	cb := t.onResize
+	t.mu.Unlock()
+	if cb != nil {
+		cb()
+	}
+}
+
+func (t *sshTty) Start() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.drained:
+		t.drained = make(chan struct{})
+	default:
+	}
+	return nil
+}
+
+func (t *sshTty) Stop() error { return nil }
+
+func (t *sshTty) Drain() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.drained:
+	default:
+		close(t.drained)
+	}
+	return nil
+}
+
+func (t *sshTty) NotifyResize(cb func()) {
+	t.mu.Lock()
+	t.onResize = cb
+	t.mu.Unlock()
+}
+
+func (t *sshTty) WindowSize() (tcell.WindowSize, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.size, nil
+}
+
+func (t *sshTty) Read(p []byte) (int, error) {
+	if len(t.pending) == 0 {
+		t.mu.Lock()
+		drained := t.drained
+		t.mu.Unlock()
+		select {
+		case data, ok := <-t.input:
+			if !ok {
+				t.mu.Lock()
+				defer t.mu.Unlock()
+				return 0, t.err
+			}
+			t.pending = data
+		case <-drained:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
+
+func (t *sshTty) Write(p []byte) (int, error) {
+	return t.channel.Write(p)
+}
+
+// Close leaves the channel open; the session closes it once the game is over
+func (t *sshTty) Close() error { return nil }
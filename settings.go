@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Settings are the choices a profile has made on the title screen that
+// carry over to its next session
+type Settings struct {
+	Difficulty string `json:"difficulty,omitempty"` // Name of the difficulty preset
+}
+
+// SettingsBook holds the settings of every profile
+type SettingsBook struct {
+	mu       sync.Mutex
+	path     string // File the settings are saved to ("" to keep them in memory)
+	profiles map[string]Settings
+}
+
+// settingsPath is where settings are kept
+func settingsPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "gninja", "settings.json")
+}
+
+// loadSettingsBook reads the settings saved at path. A missing or unreadable
+// file leaves everyone on the defaults.
+func loadSettingsBook(path string) *SettingsBook {
+	b := &SettingsBook{path: path, profiles: make(map[string]Settings)}
+	if path == "" {
+		return b
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &b.profiles)
+	}
+	return b
+}
+
+// Get returns a profile's settings
+func (b *SettingsBook) Get(profile string) Settings {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.profiles[profile]
+}
+
+// Set changes a profile's settings and saves them
+func (b *SettingsBook) Set(profile string, s Settings) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.profiles[profile] = s
+
+	if b.path == "" {
+		return nil
+	}
+	return writeJSONFile(b.path, b.profiles)
+}
+
+// loadSettings picks up the player's saved settings
+func (g *Game) loadSettings() {
+	if g.settings == nil {
+		return
+	}
+	g.difficulty, _ = difficultyNamed(g.settings.Get(g.playerName).Difficulty)
+}
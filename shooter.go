@@ -0,0 +1,200 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// Shooter patterns. Each shooting enemy is given one when it spawns.
+const (
+	PatternBurst  = "burst"  // A quick run of shots along its own row
+	PatternSpread = "spread" // A fan of three shots, the middle one at the player
+	PatternLob    = "lob"    // One slow shot thrown in an arc, to reach other rows
+)
+
+var shooterPatterns = []string{PatternBurst, PatternSpread, PatternLob}
+
+const (
+	shotRange   = 60.0 // Furthest away a shooter fires at the player
+	burstShots  = 3
+	burstGap    = 200 * time.Millisecond
+	burstSlope  = 0.15 // Steepest a burst is aimed, in rows a cell, so it stays near enough flat
+	spreadSlope = 0.5  // Steepest the middle shot of a spread is aimed
+	spreadFan   = 0.2  // Difference in slope between the shots of a spread
+	lobSpeed    = 40.0 // Horizontal speed of a lobbed shot
+	lobGravity  = 60.0
+	lobMinRange = 8.0  // Closer than this a lob goes straight up and down
+	lobMaxRise  = 60.0 // Fastest a lob is thrown upward, so it stays near the screen
+	aimMiss     = 3.0  // Rows a shot misses by (standard deviation) at zero accuracy
+)
+
+// shooterPattern picks the pattern of a new enemy ("" if it doesn't shoot)
+func (g *Game) shooterPattern(kind string) string {
+	if kind != EnemyShooter {
+		return ""
+	}
+	patterns := g.director.patterns()
+	return patterns[g.rng.Intn(len(patterns))]
+}
+
+// muzzle is where an enemy's shots start from
+func (e *Enemy) muzzle() Vec2 {
+	return Vec2{X: e.Pos.X + float64(e.Width/2), Y: e.Pos.Y + float64(e.Height/2)}
+}
+
+// aimShot works out the velocity of e's shot at target, leading them by as
+// much as the difficulty allows, along with how long it takes to get there.
+// It reports false if e's pattern has no plausible line on them.
+func (g *Game) aimShot(e *Enemy, target *Player) (vel Vec2, t float64, ok bool) {
+	from := e.muzzle()
+	to := Vec2{X: target.Pos.X + float64(target.Width)/2, Y: target.Pos.Y + float64(target.Height)/2}
+	dx := to.X - from.X
+	if math.Abs(dx) > shotRange || math.Abs(dx) < 1 || !g.inView(e.Pos.X, float64(e.Width)) {
+		return Vec2{}, 0, false
+	}
+
+	speed, gravity := g.difficulty.ShotSpeed, 0.0
+	if e.Pattern == PatternLob {
+		if math.Abs(dx) < lobMinRange {
+			return Vec2{}, 0, false
+		}
+		speed, gravity = lobSpeed, lobGravity
+	}
+
+	// Lead a running player by where they'll have got to when the shot
+	// arrives: the shot covers speed*t while they cover run*t. A player
+	// outrunning the shot can't be caught.
+	run := g.runSpeed(target) * g.difficulty.Accuracy
+	closing := speed - math.Copysign(run, dx)
+	if closing <= 0 {
+		return Vec2{}, 0, false
+	}
+	t = math.Abs(dx) / closing
+	to.X = min(max(to.X+run*t, 0), float64(g.worldWidth))
+	dx = to.X - from.X
+	if math.Abs(dx) < 1 {
+		return Vec2{}, 0, false
+	}
+	t = math.Abs(dx) / speed
+
+	// Lead a player in the air by where they'll have fallen to
+	if !target.OnGround && !target.OnPlatform {
+		lead := t * g.difficulty.Accuracy
+		to.Y += target.Vel.Y*lead + g.tuning.Gravity*lead*lead/2
+		to.Y = math.Min(to.Y, float64(g.groundY)-float64(target.Height)/2)
+	}
+
+	dy := to.Y - from.Y
+	vel = Vec2{X: math.Copysign(speed, dx), Y: dy/t - gravity*t/2}
+	switch e.Pattern {
+	case PatternSpread:
+		ok = math.Abs(dy/dx) <= spreadSlope
+	case PatternLob:
+		ok = vel.Y >= -lobMaxRise
+	default:
+		ok = math.Abs(dy/dx) <= burstSlope
+	}
+	return vel, t, ok
+}
+
+// runSpeed is how fast a player is moving sideways, as updatePlayer moves them
+func (g *Game) runSpeed(p *Player) float64 {
+	speed := g.tuning.GroundSpeed
+	if !p.OnGround {
+		speed = g.tuning.AirSpeed
+	}
+	return float64(p.MoveDir) * speed
+}
+
+// holdsLine reports whether a shooting enemy standing somewhere can shoot
+// target from there, so it needn't go looking for a line
+func (g *Game) holdsLine(e *Enemy, target *Player) bool {
+	if !e.CanShoot || e.Vel.Y != 0 {
+		return false
+	}
+	_, _, ok := g.aimShot(e, target)
+	return ok
+}
+
+// enemyShoot has a shooting enemy fire its pattern at target once it's ready
+// and has a line on them
+func (g *Game) enemyShoot(e *Enemy, target *Player) {
+	now := g.now
+	if e.LastShot.IsZero() {
+		// First shot - set initial delay
+		e.NextShotDelay = g.shotDelay()
+		e.LastShot = now
+		return
+	}
+
+	// Carry on with a burst, re-aiming each shot, while the line lasts
+	if e.Burst > 0 {
+		if now.Sub(e.LastShot) < burstGap {
+			return
+		}
+		if vel, t, ok := g.aimShot(e, target); ok {
+			g.fireShot(e, vel, t, 0)
+			e.Burst--
+		} else {
+			e.Burst = 0
+		}
+		e.LastShot = now
+		return
+	}
+
+	if now.Sub(e.LastShot) < e.NextShotDelay {
+		return
+	}
+	vel, t, ok := g.aimShot(e, target)
+	if !ok {
+		return // Hold fire until there's a line
+	}
+	switch e.Pattern {
+	case PatternSpread:
+		for k := -1.0; k <= 1; k++ {
+			fan := vel
+			fan.Y += k * spreadFan * math.Abs(vel.X)
+			g.fireShot(e, fan, t, 0)
+		}
+	case PatternLob:
+		g.fireShot(e, vel, t, lobGravity)
+	default:
+		g.fireShot(e, vel, t, 0)
+		e.Burst = burstShots - 1
+	}
+	e.LastShot = now
+	e.NextShotDelay = g.shotDelay()
+}
+
+// fireShot throws a shot from e's muzzle, off by as much as the difficulty
+// allows from where it was aimed to be t seconds on
+func (g *Game) fireShot(e *Enemy, vel Vec2, t, gravity float64) {
+	vel.Y += g.rng.NormFloat64() * aimMiss * (1 - g.difficulty.Accuracy) / t
+	e.Facing = 1
+	if vel.X < 0 {
+		e.Facing = -1
+	}
+	p := Projectile{
+		Pos:     e.muzzle(),
+		PrevPos: e.muzzle(),
+		Dir:     e.Facing,
+		Vel:     vel,
+		Gravity: gravity,
+		Active:  true,
+		Owner:   ownerEnemy,
+	}
+	g.projectiles = append(g.projectiles, p)
+	g.events.publish(ProjectileFired{Owner: ownerEnemy, Pos: p.Pos, Dir: p.Dir})
+}
+
+// velocity is how fast a projectile is moving
+func (pr *Projectile) velocity() Vec2 {
+	if pr.Vel != (Vec2{}) {
+		return pr.Vel
+	}
+	speed := playerProjectileSpeed
+	if pr.Owner == ownerEnemy {
+		speed = enemyProjectileSpeed
+	}
+	return Vec2{X: float64(pr.Dir) * speed}
+}
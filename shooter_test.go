@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestShotsLeadRunningTarget(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		keys []Action // Held every frame
+	}{
+		{"running away", []Action{ActionRight}},
+		{"running closer", []Action{ActionLeft}},
+		{"standing", nil},
+	} {
+		g := newTestGame(t)
+		pressKey(g, tcell.KeyRune, ' ')
+		g.difficulty = difficulties[len(difficulties)-1] // Dead on, so only the lead can miss
+		g.platforms, g.hazards, g.enemies = nil, nil, nil
+		g.nav = nil
+		hit := false
+		g.events.subscribe(func(ev Event) {
+			if ev, ok := ev.(PlayerHit); ok && ev.Cause == causeShuriken {
+				hit = true
+			}
+		})
+
+		ground := float64(g.groundY)
+		p := &g.players[0]
+		p.Pos = Vec2{X: 60, Y: ground - PlayerHeight}
+		p.SafeUntil = time.Time{}
+		run := func() {
+			for _, a := range tt.keys {
+				g.pressAction(p, a)
+			}
+			g.enemies = nil // Only the shot can hit them
+			g.update(1.0 / 30)
+		}
+		run()
+
+		e := Enemy{Pos: Vec2{X: 20, Y: ground - EnemyHeight}, Width: EnemyWidth, Height: EnemyHeight, Active: true, CanShoot: true, Pattern: PatternBurst}
+		vel, shotTime, ok := g.aimShot(&e, p)
+		if !ok {
+			t.Fatalf("%s: no line on the player", tt.name)
+		}
+		g.fireShot(&e, vel, shotTime, 0)
+		for f := 0; f < 60 && !hit; f++ {
+			run()
+		}
+		if !hit {
+			t.Errorf("%s: shot missed the player", tt.name)
+		}
+	}
+}
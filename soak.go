@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// A soak test has the AI play a lot of games headless, as fast as they run,
+// to shake out crashes and the simulation going wrong. Each game is on the
+// next level in the list with the next seed, so a failure can be played
+// again with the same --level and --seed.
+
+// soakMaxRun is how long a game may go on before the soak test moves on
+const soakMaxRun = 5 * time.Minute
+
+// soakResult is what a soak test found
+type soakResult struct {
+	games    int
+	clears   int
+	timeouts int            // Games still going at soakMaxRun
+	score    int            // Total over all games
+	played   time.Duration  // Total game time
+	deaths   map[string]int // By cause
+	failures []string
+}
+
+// runSoak plays games games, seeds counting up from seed, and reports on out.
+// It reports whether every game played without a failure.
+func (g *Game) runSoak(games int, seed int64, out io.Writer) bool {
+	// Nothing is saved or recorded
+	g.ghostsOn = false
+	g.savePath = ""
+	g.scores, g.stats, g.achievements = nil, nil, nil
+	g.numPlayers = 1
+	g.match = nil
+
+	res := soakResult{deaths: make(map[string]int)}
+	g.events.subscribe(func(ev Event) {
+		if ev, ok := ev.(PlayerHit); ok {
+			res.deaths[ev.Cause]++
+		}
+	})
+
+	started := time.Now()
+	for i := 0; i < games; i++ {
+		g.levelIndex = i % len(g.levels)
+		g.levelSeed = seed + int64(i)
+		if err := g.soakGame(&res); err != nil {
+			failure := fmt.Sprintf("game %d (level %q, seed %d): %v", i+1, g.levels[g.levelIndex].name, g.levelSeed, err)
+			res.failures = append(res.failures, failure)
+			fmt.Fprintln(out, "FAIL", failure)
+		}
+	}
+	res.report(out, time.Since(started))
+	return len(res.failures) == 0
+}
+
+// soakGame plays one game on the selected level, returning what went wrong
+func (g *Game) soakGame(res *soakResult) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	g.inMenu = false
+	g.buildLevel()
+	g.resetRun()
+	ai := newAIPlayer(g.levelSeed)
+	for !g.gameOver && g.now.Sub(g.runStart) < soakMaxRun {
+		ai.act(g, &g.players[0])
+		g.update(FrameDuration.Seconds())
+		if err := g.checkSane(); err != nil {
+			return fmt.Errorf("%.2fs in: %v", g.now.Sub(g.runStart).Seconds(), err)
+		}
+	}
+
+	res.games++
+	res.score += g.players[0].Score
+	res.played += g.now.Sub(g.runStart)
+	if g.stageClear {
+		res.clears++
+	}
+	if !g.gameOver {
+		res.timeouts++
+	}
+	return nil
+}
+
+// checkSane checks that everything is somewhere that makes sense
+func (g *Game) checkSane() error {
+	bad := func(v Vec2) bool {
+		return math.IsNaN(v.X) || math.IsNaN(v.Y) || math.IsInf(v.X, 0) || math.IsInf(v.Y, 0)
+	}
+	for i := range g.players {
+		p := &g.players[i]
+		if bad(p.Pos) || bad(p.Vel) {
+			return fmt.Errorf("player %d at %v moving %v", i+1, p.Pos, p.Vel)
+		}
+		if !p.Dead && (p.Pos.X < 0 || p.Pos.X+float64(p.Width) > float64(g.worldWidth)) {
+			return fmt.Errorf("player %d left the world at %v", i+1, p.Pos)
+		}
+	}
+	for i := range g.enemies {
+		e := &g.enemies[i]
+		if e.Active && (bad(e.Pos) || bad(e.Vel)) {
+			return fmt.Errorf("enemy %d at %v moving %v", e.ID, e.Pos, e.Vel)
+		}
+	}
+	for i := range g.projectiles {
+		if pr := &g.projectiles[i]; pr.Active && bad(pr.Pos) {
+			return fmt.Errorf("shuriken at %v", pr.Pos)
+		}
+	}
+	return nil
+}
+
+// report prints a summary of the soak test
+func (r *soakResult) report(out io.Writer, took time.Duration) {
+	fmt.Fprintf(out, "%d games in %s: %d cleared, %d timed out, %d failed\n",
+		r.games+len(r.failures), took.Round(time.Millisecond), r.clears, r.timeouts, len(r.failures))
+	if r.games > 0 {
+		fmt.Fprintf(out, "average score %.1f, average game %s\n",
+			float64(r.score)/float64(r.games), formatDuration((r.played / time.Duration(r.games)).Seconds()))
+	}
+	causes := make([]string, 0, len(r.deaths))
+	for cause := range r.deaths {
+		causes = append(causes, cause)
+	}
+	sort.Slice(causes, func(i, j int) bool { return r.deaths[causes[i]] > r.deaths[causes[j]] })
+	for _, cause := range causes {
+		fmt.Fprintf(out, "  died to %s: %d\n", cause, r.deaths[cause])
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Spectators watch a game without playing in it. Games running in the same
+// process (the sessions of an SSH server) are listed in a liveGames registry
+// and publish a snapshot to their watchers every frame. A network host sends
+// its snapshots to spectators along with its players.
+
+const spectatorSlot = -1 // netClient.slot of a spectator
+
+// liveGames is the list of games that can be watched
+type liveGames struct {
+	mu     sync.Mutex
+	games  []*liveGame
+	serial int
+}
+
+// liveGame is a running game's entry in the registry
+type liveGame struct {
+	ID   int
+	Name string // Who is playing
+
+	mu       sync.Mutex
+	watchers map[chan *snapshot]bool
+	ended    bool
+}
+
+// add lists a new game under the name of its player
+func (l *liveGames) add(name string) *liveGame {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.serial++
+	lg := &liveGame{ID: l.serial, Name: name, watchers: make(map[chan *snapshot]bool)}
+	l.games = append(l.games, lg)
+	return lg
+}
+
+// remove takes a game off the list once it's over, letting its watchers know
+func (l *liveGames) remove(lg *liveGame) {
+	l.mu.Lock()
+	for i, other := range l.games {
+		if other == lg {
+			l.games = append(l.games[:i], l.games[i+1:]...)
+			break
+		}
+	}
+	l.mu.Unlock()
+
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	lg.ended = true
+	for ch := range lg.watchers {
+		close(ch)
+	}
+	lg.watchers = nil
+}
+
+// next is the game step places after cur in the list, wrapping around. If cur
+// isn't listed (nil, or it has ended) it's the first or last game.
+func (l *liveGames) next(cur *liveGame, step int) *liveGame {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := len(l.games)
+	if n == 0 {
+		return nil
+	}
+	i := -1
+	for j, lg := range l.games {
+		if lg == cur {
+			i = j
+		}
+	}
+	if i < 0 && step < 0 {
+		i = 0
+	}
+	return l.games[((i+step)%n+n)%n]
+}
+
+// position is where a game is in the list, from 1, and how many games there are
+func (l *liveGames) position(lg *liveGame) (int, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, other := range l.games {
+		if other == lg {
+			return i + 1, len(l.games)
+		}
+	}
+	return 0, len(l.games)
+}
+
+// watch subscribes to a game's snapshots. The channel is closed when the game
+// ends.
+func (lg *liveGame) watch() chan *snapshot {
+	ch := make(chan *snapshot, 4)
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if lg.ended {
+		close(ch)
+		return ch
+	}
+	lg.watchers[ch] = true
+	return ch
+}
+
+func (lg *liveGame) unwatch(ch chan *snapshot) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	delete(lg.watchers, ch)
+}
+
+// watched reports whether anyone is watching
+func (lg *liveGame) watched() bool {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	return len(lg.watchers) > 0
+}
+
+// publish hands a snapshot to every watcher. The snapshot shares maps and
+// slices with the running game, so it goes through JSON to give the watchers
+// a copy of their own. Watchers that aren't keeping up miss the frame.
+func (lg *liveGame) publish(s *snapshot) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	var copied snapshot
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return
+	}
+
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	for ch := range lg.watchers {
+		select {
+		case ch <- &copied:
+		default:
+		}
+	}
+}
+
+// snapshotBuffer keeps the last two snapshots received so the frames between
+// them can be interpolated
+type snapshotBuffer struct {
+	prev, cur     *snapshot
+	prevAt, curAt time.Time
+}
+
+func (b *snapshotBuffer) push(s *snapshot) {
+	b.prev, b.prevAt = b.cur, b.curAt
+	b.cur, b.curAt = s, time.Now()
+}
+
+// drawSnapshots renders the game as it was between the last two snapshots
+func (g *Game) drawSnapshots(b *snapshotBuffer) {
+	if b.cur == nil {
+		return
+	}
+	alpha := 1.0
+	if b.prev != nil && b.curAt.After(b.prevAt) {
+		alpha = float64(time.Since(b.curAt)) / float64(b.curAt.Sub(b.prevAt))
+	}
+	g.applySnapshot(b.prev, b.cur, alpha)
+	g.renderClient()
+}
+
+// runSpectator lets a viewer watch the games in a registry, switching between
+// them with the arrow keys. Nothing the viewer presses reaches the games.
+func (g *Game) runSpectator(games *liveGames) {
+	events := make(chan tcell.Event, 10)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			ev := g.screen.PollEvent()
+			if ev == nil {
+				return // Screen finalized
+			}
+			select {
+			case events <- ev:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(FrameDuration)
+	defer ticker.Stop()
+
+	var watched *liveGame
+	var feed chan *snapshot
+	var buf snapshotBuffer
+	switchTo := func(lg *liveGame) {
+		if watched != nil {
+			watched.unwatch(feed)
+		}
+		watched, feed, buf = lg, nil, snapshotBuffer{}
+		if lg != nil {
+			feed = lg.watch()
+		}
+	}
+	defer func() { switchTo(nil) }()
+	switchTo(games.next(nil, 1))
+
+	for {
+		select {
+		case ev := <-events:
+			switch ev := ev.(type) {
+			case *tcell.EventKey:
+				switch ev.Key() {
+				case tcell.KeyEscape:
+					return
+				case tcell.KeyLeft:
+					switchTo(games.next(watched, -1))
+				case tcell.KeyRight:
+					switchTo(games.next(watched, 1))
+				case tcell.KeyTab:
+					g.bloodColorMode = (g.bloodColorMode + 1) % 4
+				}
+			case *tcell.EventResize:
+				g.width, g.height = g.screen.Size()
+			case *tcell.EventError:
+				return
+			}
+		case s, ok := <-feed:
+			if !ok {
+				// The game ended; move on to the next one
+				switchTo(games.next(watched, 1))
+				continue
+			}
+			buf.push(s)
+		case <-ticker.C:
+			if watched == nil {
+				switchTo(games.next(nil, 1))
+			}
+			if watched == nil || buf.cur == nil {
+				g.drawNothingToWatch(watched)
+				continue
+			}
+			i, n := games.position(watched)
+			g.watching = fmt.Sprintf("%s (%d/%d, ←/→ to switch)", watched.Name, i, n)
+			g.drawSnapshots(&buf)
+		}
+	}
+}
+
+// drawNothingToWatch is the spectator's screen while there's no game to show
+func (g *Game) drawNothingToWatch(waitingFor *liveGame) {
+	g.screen.Clear()
+	text := "No games running yet"
+	if waitingFor != nil {
+		text = fmt.Sprintf("Waiting for %s's game", waitingFor.Name)
+	}
+	g.drawText((g.width-len(text))/2, g.height/2-1, text, tcell.StyleDefault.Foreground(tcell.ColorWhite))
+	help := "ESC to leave"
+	g.drawText((g.width-len(help))/2, g.height/2+1, help, tcell.StyleDefault)
+	g.screen.Show()
+}
+
+// drawWatching labels a spectator's view with what they're watching
+func (g *Game) drawWatching() {
+	text := " WATCHING " + g.watching
+	g.drawText(g.width-len([]rune(text)), 0, text, tcell.StyleDefault.Foreground(tcell.ColorGray))
+}
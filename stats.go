@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Causes of death. Hazard deaths use the hazard's kind.
+const (
+	causeTouch    = "touch"    // Walked into an enemy
+	causeShuriken = "shuriken" // Hit by an enemy's (or in versus, a rival's) shuriken
+)
+
+// causeText describes a cause of death for the end of run summary
+var causeText = map[string]string{
+	causeTouch:    "cut down by an enemy",
+	causeShuriken: "hit by a shuriken",
+	HazardSpikes:  "impaled on spikes",
+	HazardFire:    "burned by a fire vent",
+	HazardPit:     "fell into a pit",
+}
+
+// Stats are combat statistics for player one, kept for a run and added up
+// over a profile's lifetime
+type Stats struct {
+	Runs          int            `json:"runs"`
+	Kills         int            `json:"kills"`
+	Decapitations int            `json:"decapitations"`
+	ShotsFired    int            `json:"shotsFired"`
+	ShotsHit      int            `json:"shotsHit"`
+	Deaths        map[string]int `json:"deaths,omitempty"` // Keyed by cause
+	LongestRun    float64        `json:"longestRun"`       // Seconds
+	PlayTime      float64        `json:"playTime"`         // Seconds
+}
+
+// accuracy is the share of shots fired that hit something, in percent
+func (s *Stats) accuracy() int {
+	if s.ShotsFired == 0 {
+		return 0
+	}
+	return s.ShotsHit * 100 / s.ShotsFired
+}
+
+// add folds a finished run into lifetime stats
+func (s *Stats) add(run Stats) {
+	s.Runs++
+	s.Kills += run.Kills
+	s.Decapitations += run.Decapitations
+	s.ShotsFired += run.ShotsFired
+	s.ShotsHit += run.ShotsHit
+	for cause, n := range run.Deaths {
+		if s.Deaths == nil {
+			s.Deaths = make(map[string]int)
+		}
+		s.Deaths[cause] += n
+	}
+	s.LongestRun = max(s.LongestRun, run.PlayTime)
+	s.PlayTime += run.PlayTime
+}
+
+// StatsBook holds the lifetime stats of every profile
+type StatsBook struct {
+	mu       sync.Mutex
+	path     string // File the stats are saved to ("" to keep them in memory)
+	profiles map[string]*Stats
+}
+
+// statsPath is where lifetime stats are kept
+func statsPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "gninja", "stats.json")
+}
+
+// loadStatsBook reads the stats saved at path. A missing or unreadable file
+// starts everyone from zero.
+func loadStatsBook(path string) *StatsBook {
+	b := &StatsBook{path: path, profiles: make(map[string]*Stats)}
+	if path == "" {
+		return b
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &b.profiles)
+	}
+	return b
+}
+
+// Get returns a profile's lifetime stats
+func (b *StatsBook) Get(profile string) Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s := b.profiles[profile]; s != nil {
+		return *s
+	}
+	return Stats{}
+}
+
+// Add folds a finished run into a profile's lifetime stats and saves them
+func (b *StatsBook) Add(profile string, run Stats) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.profiles[profile]
+	if s == nil {
+		s = &Stats{}
+		b.profiles[profile] = s
+	}
+	s.add(run)
+
+	if b.path == "" {
+		return nil
+	}
+	return writeJSONFile(b.path, b.profiles)
+}
+
+// countsForStats reports whether something done by player i goes into the
+// stats: only player one's doings count, and not in versus matches, editor
+// playtests or the menu demo (the same runs high scores leave out)
+func (g *Game) countsForStats(i int) bool {
+	return i == 0 && g.match == nil && g.editor == nil && !g.inMenu
+}
+
+// statsEvent adds what happens in a run to its stats
+func (g *Game) statsEvent(ev Event) {
+	s := &g.runStats
+	switch ev := ev.(type) {
+	case ProjectileFired:
+		if g.countsForStats(ev.Owner) {
+			s.ShotsFired++
+		}
+	case EnemyKilled:
+		if g.countsForStats(ev.By) {
+			s.Kills++
+			if ev.Cause == causeShuriken {
+				s.ShotsHit++
+			}
+		}
+	case Decapitation:
+		if g.countsForStats(ev.By) {
+			s.Decapitations++
+		}
+	case PlayerHit:
+		g.lastDeath = ev.Cause
+		if g.countsForStats(ev.Player) {
+			if s.Deaths == nil {
+				s.Deaths = make(map[string]int)
+			}
+			s.Deaths[ev.Cause]++
+		}
+	}
+}
+
+// recordStats adds the run that just ended to the player's lifetime stats
+func (g *Game) recordStats() {
+	g.runStats.PlayTime = g.runTime.Seconds()
+	if g.stats == nil || g.match != nil || g.editor != nil || g.inMenu {
+		return
+	}
+	if err := g.stats.Add(g.playerName, g.runStats); err != nil {
+		g.toast("Stats not saved: " + err.Error())
+	}
+}
+
+// formatDuration formats a number of seconds like "1:05"
+func formatDuration(seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// runSummary is a line about the run that just ended for the game over screen
+func (g *Game) runSummary() string {
+	s := &g.runStats
+	return fmt.Sprintf("%d kills, %d decapitations, %d%% accuracy (%d/%d), %s",
+		s.Kills, s.Decapitations, s.accuracy(), s.ShotsHit, s.ShotsFired, formatDuration(s.PlayTime))
+}
+
+// drawStats draws the lifetime stats page of the menu
+func (g *Game) drawStats() {
+	var s Stats
+	if g.stats != nil {
+		s = g.stats.Get(g.playerName)
+	}
+
+	lines := []string{
+		fmt.Sprintf("Runs played      %d", s.Runs),
+		fmt.Sprintf("Play time        %s", formatDuration(s.PlayTime)),
+		fmt.Sprintf("Longest run      %s", formatDuration(s.LongestRun)),
+		fmt.Sprintf("Kills            %d", s.Kills),
+		fmt.Sprintf("Decapitations    %d", s.Decapitations),
+		fmt.Sprintf("Shots fired      %d", s.ShotsFired),
+		fmt.Sprintf("Shots hit        %d", s.ShotsHit),
+		fmt.Sprintf("Accuracy         %d%%", s.accuracy()),
+	}
+	causes := make([]string, 0, len(s.Deaths))
+	for cause := range s.Deaths {
+		causes = append(causes, cause)
+	}
+	sort.Slice(causes, func(i, j int) bool { return s.Deaths[causes[i]] > s.Deaths[causes[j]] })
+	deaths := 0
+	for _, cause := range causes {
+		deaths += s.Deaths[cause]
+	}
+	lines = append(lines, fmt.Sprintf("Deaths           %d", deaths))
+	for _, cause := range causes {
+		lines = append(lines, fmt.Sprintf("  %-22s %d", causeText[cause], s.Deaths[cause]))
+	}
+
+	title := "Stats for " + g.playerName
+	if g.playerName == "" {
+		title = "Stats"
+	}
+	titleY := max(1, g.height/2-len(lines)/2-2)
+	g.drawText((g.width-len(title))/2, titleY, title, tcell.StyleDefault.Foreground(tcell.ColorGreen))
+
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line))
+	}
+	for i, line := range lines {
+		g.drawText((g.width-width)/2, titleY+2+i, line, tcell.StyleDefault)
+	}
+
+	help := "ESC to go back"
+	g.drawText((g.width-len(help))/2, titleY+3+len(lines), help, tcell.StyleDefault)
+}
+
+func (g *Game) handleStatsInput(ev *tcell.EventKey) {
+	if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyEnter {
+		g.menuScreen = screenMain
+	}
+}
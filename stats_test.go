@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestStatsLeaveOutVersus(t *testing.T) {
+	tests := []struct {
+		name string
+		key  rune
+		runs int
+	}{
+		{"solo", ' ', 1},
+		{"versus", 'v', 0},
+	}
+	for _, tt := range tests {
+		g := newTestGame(t)
+		g.stats = loadStatsBook("")
+		pressKey(g, tcell.KeyRune, tt.key)
+		g.events.publish(ProjectileFired{Owner: 0})
+		g.events.publish(PlayerHit{Player: 1, Cause: causeShuriken, By: 0})
+		g.recordStats()
+
+		s := g.stats.Get(g.playerName)
+		if s.Runs != tt.runs || s.ShotsFired != tt.runs || s.ShotsHit != 0 {
+			t.Errorf("%s: %d runs, %d shots fired, %d hit; want %d, %d, 0", tt.name, s.Runs, s.ShotsFired, s.ShotsHit, tt.runs, tt.runs)
+		}
+	}
+}
+
+func TestHazardsScoreNothingAfterGameOver(t *testing.T) {
+	g := newTestGame(t)
+	pressKey(g, tcell.KeyRune, ' ')
+	kills := 0
+	g.events.subscribe(func(ev Event) {
+		if _, ok := ev.(EnemyKilled); ok {
+			kills++
+		}
+	})
+	g.gameOver = true
+	g.hazards = []Hazard{{Kind: HazardSpikes, X: 40, Y: float64(g.groundY) - 1, Width: 5}}
+	g.enemies = []Enemy{{Pos: Vec2{X: 40, Y: float64(g.groundY) - EnemyHeight}, Width: EnemyWidth, Height: EnemyHeight, Active: true}}
+	g.checkEnemyHazards()
+
+	if g.enemies[0].Active {
+		t.Fatal("enemy in the spikes is still alive")
+	}
+	if kills != 0 || g.enemiesDefeated != 0 {
+		t.Errorf("%d kills published and %d enemies defeated after game over, want none", kills, g.enemiesDefeated)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Enemies going after the same player work together. Each frame a director
+// gives them roles: chasers close in from both sides, and extra chasers on a
+// side nobody is coming from the other way hold back for a while, so they
+// spring a pincer once someone is. Shooters on a side space themselves out
+// rather than all keeping the same distance.
+
+// Roles the director gives enemies
+const (
+	roleChase = iota // Go straight for the player
+	roleHold         // Wait Post away for a chaser on the other side
+	roleShoot        // Keep Post away and shoot
+)
+
+const (
+	holdDistance     = 14.0 // How far from the player the first enemy holding back waits
+	holdSpacing      = 6.0  // Between enemies holding back on the same side
+	holdMin          = 2 * time.Second
+	holdMax          = 4 * time.Second
+	shooterDistance  = 30.0 // How far from the player the nearest shooter on a side keeps
+	shooterSpacing   = 12.0 // Between the shooters on a side
+	spawnFlankChance = 0.75 // Chance a new enemy comes in on the side fewer are coming from
+)
+
+// flank is the enemies coming at a player from one side
+type flank struct {
+	chasers  []*Enemy
+	shooters []*Enemy
+}
+
+// directEnemies gives every enemy a role in going after its nearest player
+func (g *Game) directEnemies() {
+	flanks := make(map[*Player]*[2]flank)
+	for i := range g.enemies {
+		e := &g.enemies[i]
+		target := g.nearestPlayer(e.Pos)
+		if !e.Active || target == nil {
+			continue
+		}
+		if flanks[target] == nil {
+			flanks[target] = &[2]flank{}
+		}
+		side := &flanks[target][0]
+		if e.Pos.X > target.Pos.X {
+			side = &flanks[target][1]
+		}
+		if e.CanShoot {
+			side.shooters = append(side.shooters, e)
+		} else {
+			side.chasers = append(side.chasers, e)
+		}
+	}
+
+	for i := range g.players {
+		target := &g.players[i]
+		sides := flanks[target]
+		if sides == nil {
+			continue
+		}
+		for s := range sides {
+			side, other := &sides[s], &sides[1-s]
+			byDistance(side.shooters, target)
+			byDistance(side.chasers, target)
+
+			for k, e := range side.shooters {
+				e.Role = roleShoot
+				e.Post = shooterDistance + float64(k)*shooterSpacing
+			}
+			for k, e := range side.chasers {
+				e.Role = roleChase
+				if k == 0 || len(other.chasers) > 0 {
+					continue // Leading, or the pincer's closing
+				}
+				if e.HoldUntil.IsZero() {
+					e.HoldUntil = g.now.Add(holdMin + time.Duration(g.rng.Int63n(int64(holdMax-holdMin))))
+				}
+				if g.now.Before(e.HoldUntil) {
+					e.Role = roleHold
+					e.Post = holdDistance + float64(k-1)*holdSpacing
+				}
+			}
+		}
+	}
+}
+
+// byDistance sorts enemies nearest target first
+func byDistance(enemies []*Enemy, target *Player) {
+	sort.SliceStable(enemies, func(i, j int) bool {
+		return math.Abs(enemies[i].Pos.X-target.Pos.X) < math.Abs(enemies[j].Pos.X-target.Pos.X)
+	})
+}
+
+// thinSide is the side of the players fewer chasers are coming from (-1 for
+// the left, 1 for the right), or 0 if it's even
+func (g *Game) thinSide() int {
+	n := 0
+	for i := range g.enemies {
+		e := &g.enemies[i]
+		if !e.Active || e.CanShoot {
+			continue
+		}
+		if p := g.nearestPlayer(e.Pos); p != nil {
+			if e.Pos.X < p.Pos.X {
+				n--
+			} else {
+				n++
+			}
+		}
+	}
+	switch {
+	case n < 0:
+		return 1
+	case n > 0:
+		return -1
+	}
+	return 0
+}
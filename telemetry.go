@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// telemetryInterval is how often the entity counts are logged
+const telemetryInterval = time.Second
+
+// telemetry logs a session for balancing: one JSON object per line for every
+// notable event, plus the number of things on screen every second. Times are
+// seconds of game clock since the run started.
+type telemetry struct {
+	game    *Game
+	file    *os.File
+	w       *bufio.Writer
+	enc     *json.Encoder
+	err     error             // First write error; nothing more is written after one
+	run     int               // Runs started this session
+	spawned map[int]time.Time // When each enemy still alive spawned, for time to kill
+	counted time.Time         // When the entity counts were last logged
+}
+
+// telemetryRecord is one line of the log
+type telemetryRecord map[string]any
+
+// startTelemetry creates the log at path and subscribes it to g's events
+func startTelemetry(g *Game, path string) (*telemetry, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &telemetry{game: g, file: f, w: bufio.NewWriter(f), spawned: make(map[int]time.Time)}
+	t.enc = json.NewEncoder(t.w)
+	g.telemetry = t
+	g.events.subscribe(t.event)
+	return t, nil
+}
+
+// write logs a record, stamped with the time and run
+func (t *telemetry) write(event string, rec telemetryRecord) {
+	if t.err != nil {
+		return
+	}
+	g := t.game
+	if rec == nil {
+		rec = telemetryRecord{}
+	}
+	rec["t"] = g.now.Sub(g.runStart).Round(time.Millisecond).Seconds()
+	rec["run"] = t.run
+	rec["event"] = event
+	t.err = t.enc.Encode(rec)
+}
+
+// event logs an event from the game. The menu demo's runs aren't logged.
+func (t *telemetry) event(ev Event) {
+	g := t.game
+	if g.inMenu {
+		return
+	}
+	switch ev := ev.(type) {
+	case RunStarted:
+		t.run++
+		t.spawned = make(map[int]time.Time)
+		t.counted = g.now
+		t.write("run_start", telemetryRecord{"level": ev.Level, "seed": ev.Seed, "players": ev.Players, "versus": ev.Versus, "resumed": ev.Resumed, "difficulty": ev.Difficulty})
+	case RunEnded:
+		t.write("run_end", telemetryRecord{"scores": ev.Scores, "time": ev.Time, "clear": ev.Clear})
+	case EnemySpawned:
+		t.spawned[ev.EnemyID] = g.now
+		t.write("spawn", telemetryRecord{"id": ev.EnemyID, "kind": ev.Kind, "side": t.side(ev.Pos), "x": ev.Pos.X, "y": ev.Pos.Y})
+	case ProjectileFired:
+		t.write("shot", telemetryRecord{"owner": ev.Owner, "x": ev.Pos.X, "y": ev.Pos.Y, "dir": ev.Dir})
+	case EnemyKilled:
+		if ev.Cause == causeShuriken {
+			t.write("hit", telemetryRecord{"owner": ev.By, "target": "enemy", "id": ev.EnemyID})
+		}
+		rec := telemetryRecord{"id": ev.EnemyID, "kind": ev.Kind, "cause": ev.Cause, "by": ev.By, "x": ev.Pos.X, "y": ev.Pos.Y}
+		if at, ok := t.spawned[ev.EnemyID]; ok {
+			rec["ttk"] = g.now.Sub(at).Round(time.Millisecond).Seconds()
+			delete(t.spawned, ev.EnemyID)
+		}
+		t.write("kill", rec)
+	case Decapitation:
+		t.write("decapitation", telemetryRecord{"id": ev.EnemyID, "by": ev.By})
+	case PlayerHit:
+		if ev.Cause == causeShuriken {
+			t.write("hit", telemetryRecord{"owner": ev.By, "target": "player", "player": ev.Player})
+		}
+		t.write("death", telemetryRecord{"player": ev.Player, "cause": ev.Cause, "by": ev.By, "lives": ev.LivesLeft, "x": ev.Pos.X, "y": ev.Pos.Y})
+	case SpawnRateChanged:
+		t.write("spawn_rate", telemetryRecord{"rate": ev.Rate, "phase": ev.Phase, "defeated": ev.EnemiesDefeated})
+	}
+}
+
+// side is which side of the nearest player an enemy spawned on
+func (t *telemetry) side(pos Vec2) string {
+	g := t.game
+	x := float64(g.worldWidth) / 2
+	if p := g.nearestPlayer(pos); p != nil {
+		x = p.Pos.X
+	}
+	if pos.X < x {
+		return "left"
+	}
+	return "right"
+}
+
+// update logs the entity counts once a second during a run
+func (t *telemetry) update() {
+	g := t.game
+	if g.inMenu || g.now.Sub(t.counted) < telemetryInterval {
+		return
+	}
+	t.counted = t.counted.Add(telemetryInterval)
+	enemies, shooters, projectiles, corpses, alive := 0, 0, 0, 0, 0
+	for i := range g.enemies {
+		if g.enemies[i].Active {
+			enemies++
+			if g.enemies[i].CanShoot {
+				shooters++
+			}
+		}
+	}
+	for i := range g.projectiles {
+		if g.projectiles[i].Active {
+			projectiles++
+		}
+	}
+	for i := range g.corpses {
+		if g.corpses[i].Active {
+			corpses++
+		}
+	}
+	for i := range g.players {
+		if !g.players[i].Dead {
+			alive++
+		}
+	}
+	t.write("counts", telemetryRecord{
+		"enemies":     enemies,
+		"shooters":    shooters,
+		"projectiles": projectiles,
+		"corpses":     corpses,
+		"particles":   len(g.deathParticles) + len(g.bloodParticles),
+		"players":     alive,
+		"defeated":    g.enemiesDefeated,
+		"spawn_rate":  g.spawnRate,
+	})
+}
+
+// close flushes the log and reports the first error writing it
+func (t *telemetry) close() error {
+	if t.err == nil {
+		t.err = t.w.Flush()
+	}
+	if err := t.file.Close(); t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
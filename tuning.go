@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Tuning is the physics and feel of the game, which designers can change
+// without recompiling: the game reads ~/.config/gninja/tuning.json (or the
+// file given with --tuning) when it starts, and again whenever it changes.
+// Fields missing from the file keep their defaults.
+type Tuning struct {
+	Gravity           float64 `json:"gravity"`           // Pulls players, enemies and particles down, in cells a second squared
+	JumpSpeed         float64 `json:"jumpSpeed"`         // Upward speed of a jump, for players and enemies (negative is up)
+	GroundSpeed       float64 `json:"groundSpeed"`       // How fast players run
+	AirSpeed          float64 `json:"airSpeed"`          // How fast players move in the air
+	RedChance         float64 `json:"redChance"`         // Chance a body part is bloodied
+	FallThroughChance float64 `json:"fallThroughChance"` // Chance a body part falls through the ground
+	DecapChance       float64 `json:"decapChance"`       // Chance a killed enemy loses its head and keeps walking
+	HeadRollChance    float64 `json:"headRollChance"`    // Chance a head rolls away after bouncing
+}
+
+var defaultTuning = Tuning{
+	Gravity:           300,
+	JumpSpeed:         -85,
+	GroundSpeed:       50,
+	AirSpeed:          45,
+	RedChance:         0.2,
+	FallThroughChance: 0.3,
+	DecapChance:       0.1,
+	HeadRollChance:    0.05,
+}
+
+// tuningCheck is how often the tuning file is checked for changes
+const tuningCheck = time.Second
+
+// tuningPath is where the tuning file is kept
+func tuningPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "gninja", "tuning.json")
+}
+
+// loadTuning reads the tuning file at path. A missing file gives the
+// defaults.
+func loadTuning(path string) (Tuning, error) {
+	t := defaultTuning
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return t, fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	return t, t.check(filepath.Base(path))
+}
+
+// check reports numbers that would break the game
+func (t *Tuning) check(file string) error {
+	switch {
+	case t.Gravity <= 0:
+		return fmt.Errorf("%s: gravity must be more than 0", file)
+	case t.JumpSpeed >= 0:
+		return fmt.Errorf("%s: jumpSpeed must be negative (up)", file)
+	case t.GroundSpeed <= 0 || t.AirSpeed <= 0:
+		return fmt.Errorf("%s: groundSpeed and airSpeed must be more than 0", file)
+	}
+	for _, chance := range []float64{t.RedChance, t.FallThroughChance, t.DecapChance, t.HeadRollChance} {
+		if chance < 0 || chance > 1 {
+			return fmt.Errorf("%s: chances must be from 0 to 1", file)
+		}
+	}
+	return nil
+}
+
+// watchTuning reads the tuning file if it has changed since the game last
+// looked. A file that doesn't load leaves the tuning as it was and says why.
+func (g *Game) watchTuning() {
+	if g.tuningPath == "" || time.Since(g.tuningChecked) < tuningCheck {
+		return
+	}
+	first := g.tuningChecked.IsZero()
+	g.tuningChecked = time.Now()
+	var modified time.Time
+	if info, err := os.Stat(g.tuningPath); err == nil {
+		modified = info.ModTime()
+	}
+	if modified.Equal(g.tuningModified) {
+		return
+	}
+	g.tuningModified = modified
+
+	t, err := loadTuning(g.tuningPath)
+	if err != nil {
+		g.toast(err.Error())
+		return
+	}
+	g.tuning = t
+	g.nav = nil // Enemies may jump differently
+	if g.inMenu && g.editor == nil {
+		// Lay the title screen's level out again for the new jump; runs
+		// already do that when they start
+		g.buildLevel()
+		g.resetPlayers()
+		g.demo = nil
+	}
+	if !first {
+		g.toast("Tuning reloaded")
+	}
+}
+
+// playerModel is how players move with the current tuning
+func (g *Game) playerModel() jumpModel {
+	m := playerJump
+	m.Gravity, m.JumpSpeed, m.AirSpeed = g.tuning.Gravity, g.tuning.JumpSpeed, g.tuning.AirSpeed
+	return m
+}
+
+// enemyModel is how enemies move with the current tuning and difficulty
+func (g *Game) enemyModel() jumpModel {
+	m := enemyJump
+	m.Gravity, m.JumpSpeed, m.AirSpeed = g.tuning.Gravity, g.tuning.JumpSpeed, g.difficulty.EnemySpeed
+	return m
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const roundPause = 2 * time.Second // How long the result of a round stays up before the next one
+
+// Match is the state of a versus match: two ninjas duel, a round ends on a
+// kill, and the first to win RoundsToWin rounds takes the match
+type Match struct {
+	RoundsToWin int
+	Enemies     bool      // Neutral enemies keep spawning and go after both players
+	Wins        []int     // Rounds won by each player
+	Round       int       // Round being fought, from 1
+	RoundWinner int       // Winner of the round that just ended (-1 for a draw)
+	RoundOverAt time.Time // When the current round ended (zero while it's being fought)
+	Winner      int       // Winner of the match (-1 until someone has RoundsToWin)
+}
+
+// startVersus starts a versus match on the selected level
+func (g *Game) startVersus() {
+	g.numPlayers = g.multiplayerCount()
+	g.match = &Match{
+		RoundsToWin: g.versusRounds,
+		Enemies:     g.versusEnemies,
+		Wins:        make([]int, g.numPlayers),
+		Winner:      -1,
+	}
+	g.inMenu = false
+	g.buildLevel()
+	g.startRound()
+}
+
+// startRound clears the arena and puts the players back at their starting spots
+func (g *Game) startRound() {
+	g.resetRun()
+	g.match.Round++
+	g.match.RoundOverAt = time.Time{}
+}
+
+// versusSpawn is where player i starts a round: spread evenly across the
+// screen, facing the middle, moved along until clear of hazards
+func (g *Game) versusSpawn(i int) (Vec2, int) {
+	n := len(g.players)
+	x := g.width*(2*i+1)/(2*n) - PlayerWidth/2 // A quarter of the way in from each side for two
+	facing := 1
+	if x+PlayerWidth/2 > g.width/2 {
+		facing = -1
+	}
+	for step := 0; step < g.width/4 && g.nearHazard(float64(x), PlayerWidth); step++ {
+		x -= facing // Back off toward the wall
+	}
+	return Vec2{X: float64(x), Y: float64(g.groundY - PlayerHeight)}, facing
+}
+
+// nearHazard reports whether any hazard is in the columns [x, x+width)
+func (g *Game) nearHazard(x float64, width int) bool {
+	for _, h := range g.hazards {
+		if x < h.X+h.Width && x+float64(width) > h.X {
+			return true
+		}
+	}
+	return false
+}
+
+// updateMatch ends the round once one player or neither is left standing,
+// and starts the next round after a pause
+func (g *Game) updateMatch() {
+	m := g.match
+	if !m.RoundOverAt.IsZero() {
+		if g.now.Sub(m.RoundOverAt) >= roundPause {
+			g.startRound()
+		}
+		return
+	}
+
+	standing := -1
+	for i := range g.players {
+		if !g.players[i].Dead {
+			if standing >= 0 {
+				return // Still a fight
+			}
+			standing = i
+		}
+	}
+
+	m.RoundOverAt = g.now
+	m.RoundWinner = standing
+	if standing < 0 {
+		return // Both went down at once: nobody gets the round
+	}
+	m.Wins[standing]++
+	if m.Wins[standing] >= m.RoundsToWin {
+		m.Winner = standing
+		g.gameOver = true
+	}
+}
+
+// winsText is the running score of the match, like "2-1"
+func (m *Match) winsText() string {
+	wins := make([]string, len(m.Wins))
+	for i, w := range m.Wins {
+		wins[i] = fmt.Sprint(w)
+	}
+	return strings.Join(wins, "-")
+}
+
+// drawMatchScore draws each player's round wins in their color
+func (g *Game) drawMatchScore() {
+	x := 0
+	for i := range g.players {
+		text := fmt.Sprintf("P%d: %d  ", i+1, g.match.Wins[i])
+		x = g.drawText(x, 0, text, tcell.StyleDefault.Foreground(g.players[i].Color))
+	}
+	text := fmt.Sprintf("Round %d, first to %d", g.match.Round, g.match.RoundsToWin)
+	g.drawText(x, 0, text, tcell.StyleDefault.Foreground(tcell.ColorWhite))
+}
+
+// drawRoundOver announces who took the round that just ended
+func (g *Game) drawRoundOver() {
+	m := g.match
+	if m.RoundOverAt.IsZero() || g.gameOver {
+		return
+	}
+	text := "DRAW"
+	style := tcell.StyleDefault.Foreground(tcell.ColorWhite).Bold(true)
+	if m.RoundWinner >= 0 {
+		text = fmt.Sprintf("P%d TAKES ROUND %d (%s)", m.RoundWinner+1, m.Round, m.winsText())
+		style = tcell.StyleDefault.Foreground(g.players[m.RoundWinner].Color).Bold(true)
+	}
+	g.drawText((g.width-len(text))/2, 2, text, style)
+}